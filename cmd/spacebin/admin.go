@@ -0,0 +1,291 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/util"
+)
+
+const adminUsage = `Usage: spacebin admin <command>
+
+Commands work directly against the database in SPIRIT_CONNECTION_URI, and
+print JSON.
+
+  list [-prefix p] [-older-than age] [-newer-than age] [-min-size n] [-max-size n] [-limit n]
+                                 List documents, oldest first, without their content
+  show <id>                      Print a document, with its content
+  delete <id>                    Delete a document
+  purge [-dry-run] -older-than age | -expired
+                                 Delete documents older than age, or that have expired,
+                                 except for custom documents
+  stats                          Count documents, and how large they are
+
+Ages are durations like 36h, or a number of days like 30d.
+`
+
+// documentSummary is how documents are listed, without their content.
+type documentSummary struct {
+	ID            string     `json:"id"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	BurnAfterRead bool       `json:"burn_after_read"`
+	Language      string     `json:"language"`
+	ContentType   string     `json:"content_type"`
+	ContentHash   string     `json:"content_hash"`
+	Size          int64      `json:"size"`
+}
+
+// admin runs commands operators use to look after the documents on an instance.
+func admin(args []string) {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, adminUsage)
+		os.Exit(2)
+	}
+
+	command, args := args[0], args[1:]
+
+	if command == "help" || command == "-h" || command == "-help" || command == "--help" {
+		fmt.Print(adminUsage)
+		return
+	}
+
+	loadConfig()
+	db, err := openDatabase(config.Config.ConnectionURI, config.Config.ContentStore)
+
+	if err != nil {
+		fatal(fmt.Errorf("could not connect to database: %w", err))
+	}
+
+	// Documents changed here mustn't be left in the cache the server reads from
+	if db, err = cacheDatabase(db); err != nil {
+		fatal(fmt.Errorf("could not set up document cache: %w", err))
+	}
+
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch command {
+	case "list":
+		err = adminList(ctx, db, args)
+	case "show":
+		err = adminShow(ctx, db, args)
+	case "delete":
+		err = adminDelete(ctx, db, args)
+	case "purge":
+		err = adminPurge(ctx, db, args)
+	case "stats":
+		err = adminStats(ctx, db, args)
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown admin command %q\n\n%s", command, adminUsage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		fatal(err)
+	}
+}
+
+func adminList(ctx context.Context, db database.Database, args []string) error {
+	flags := flag.NewFlagSet("admin list", flag.ExitOnError)
+	prefix := flags.String("prefix", "", "only documents whose IDs start with this")
+	olderThan := flags.String("older-than", "", "only documents created longer ago than this age")
+	newerThan := flags.String("newer-than", "", "only documents created more recently than this age")
+	minSize := flags.Int64("min-size", 0, "only documents of at least this many bytes")
+	maxSize := flags.Int64("max-size", 0, "only documents of at most this many bytes")
+	limit := flags.Int("limit", 0, "most documents to list (0 for all of them)")
+	flags.Parse(args)
+
+	filter := database.DocumentFilter{Prefix: *prefix, MinSize: *minSize, MaxSize: *maxSize, Limit: *limit}
+	var err error
+
+	if filter.CreatedBefore, err = ageCutoff(*olderThan); err != nil {
+		return err
+	}
+
+	if filter.CreatedAfter, err = ageCutoff(*newerThan); err != nil {
+		return err
+	}
+
+	documents, err := db.ListDocuments(ctx, filter)
+
+	if err != nil {
+		return err
+	}
+
+	summaries := make([]documentSummary, len(documents))
+
+	for i, d := range documents {
+		summaries[i] = documentSummary{d.ID, d.CreatedAt, d.UpdatedAt, d.ExpiresAt, d.BurnAfterRead, d.Language,
+			d.ContentType, d.ContentHash, d.Size}
+	}
+
+	printJSON(summaries)
+	return nil
+}
+
+func adminShow(ctx context.Context, db database.Database, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: spacebin admin show <id>")
+	}
+
+	// Unlike on the web, showing a burn after reading document here doesn't burn it
+	document, err := db.GetDocument(ctx, args[0])
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("document %q not found", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	printJSON(document)
+	return nil
+}
+
+func adminDelete(ctx context.Context, db database.Database, args []string) error {
+	if len(args) != 1 {
+		return fmt.Errorf("usage: spacebin admin delete <id>")
+	}
+
+	err := db.DeleteDocument(ctx, args[0])
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("document %q not found", args[0])
+	}
+
+	if err != nil {
+		return err
+	}
+
+	printJSON(map[string]interface{}{"id": args[0], "deleted": true})
+	return nil
+}
+
+func adminPurge(ctx context.Context, db database.Database, args []string) error {
+	flags := flag.NewFlagSet("admin purge", flag.ExitOnError)
+	olderThan := flags.String("older-than", "", "delete documents created longer ago than this age")
+	expired := flags.Bool("expired", false, "delete documents that have expired")
+	dryRun := flags.Bool("dry-run", false, "only count the documents that would be deleted")
+	flags.Parse(args)
+
+	cutoff, err := ageCutoff(*olderThan)
+
+	if err != nil {
+		return err
+	}
+
+	// Purging everything by accident is too easy, so something has to be picked
+	if cutoff.IsZero() == !*expired {
+		return fmt.Errorf("purge needs either -older-than or -expired")
+	}
+
+	// Custom documents never expire, so they're kept as the reaper keeps them
+	documents, err := util.ParseDocumentsList(config.Config.Documents)
+
+	if err != nil {
+		return err
+	}
+
+	filter := database.DocumentFilter{CreatedBefore: cutoff}
+
+	for id := range documents {
+		filter.Except = append(filter.Except, id)
+	}
+
+	if *expired {
+		filter.ExpiredBy = time.Now()
+	}
+
+	if *dryRun {
+		count, err := db.CountDocuments(ctx, filter)
+
+		if err != nil {
+			return err
+		}
+
+		printJSON(map[string]int64{"matched": count.Documents, "size": count.Size})
+		return nil
+	}
+
+	n, err := db.DeleteDocuments(ctx, filter)
+
+	if err != nil {
+		return err
+	}
+
+	printJSON(map[string]int64{"deleted": n})
+	return nil
+}
+
+func adminStats(ctx context.Context, db database.Database, args []string) error {
+	if len(args) != 0 {
+		return fmt.Errorf("usage: spacebin admin stats")
+	}
+
+	all, err := db.CountDocuments(ctx, database.DocumentFilter{})
+
+	if err != nil {
+		return err
+	}
+
+	// Expired documents are left until the reaper next runs
+	expired, err := db.CountDocuments(ctx, database.DocumentFilter{ExpiredBy: time.Now()})
+
+	if err != nil {
+		return err
+	}
+
+	printJSON(map[string]int64{"documents": all.Documents, "size": all.Size, "expired": expired.Documents})
+	return nil
+}
+
+// ageCutoff returns the time an age was before now, or the zero time if age is
+// blank. Ages are durations, or a number of days like 30d.
+func ageCutoff(age string) (time.Time, error) {
+	if age == "" {
+		return time.Time{}, nil
+	}
+
+	d, err := time.ParseDuration(age)
+
+	if days, ok := strings.CutSuffix(age, "d"); ok {
+		var n int
+		n, err = strconv.Atoi(days)
+		d = time.Duration(n) * 24 * time.Hour
+	}
+
+	if err != nil || d <= 0 {
+		return time.Time{}, fmt.Errorf("invalid age %q", age)
+	}
+
+	return time.Now().Add(-d), nil
+}
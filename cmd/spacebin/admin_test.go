@@ -0,0 +1,157 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/stretchr/testify/require"
+)
+
+// captureStdout returns what f prints to standard output.
+func captureStdout(t *testing.T, f func() error) string {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = w
+
+	defer func() { os.Stdout = stdout }()
+
+	require.NoError(t, f())
+	w.Close()
+
+	out, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(out)
+}
+
+func TestAgeCutoff(t *testing.T) {
+	cutoff, err := ageCutoff("")
+	require.NoError(t, err)
+	require.True(t, cutoff.IsZero())
+
+	for age, d := range map[string]time.Duration{
+		"36h": 36 * time.Hour,
+		"90m": 90 * time.Minute,
+		"30d": 30 * 24 * time.Hour,
+		"1d":  24 * time.Hour,
+	} {
+		cutoff, err := ageCutoff(age)
+		require.NoError(t, err, age)
+		require.WithinDuration(t, time.Now().Add(-d), cutoff, time.Second, age)
+	}
+
+	for _, age := range []string{"0d", "-1h", "0s", "d", "1.5d", "30days", "soon"} {
+		_, err := ageCutoff(age)
+		require.Error(t, err, age)
+	}
+}
+
+func TestAdminList(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	now := time.Now().UTC()
+
+	for _, document := range []database.Document{
+		{ID: "aaaaaaaa", Content: "old", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "aaaabbbb", Content: "a little newer", CreatedAt: now.Add(-2 * 24 * time.Hour)},
+		{ID: "bbbbbbbb", Content: "new, and the largest of them all", CreatedAt: now.Add(-time.Hour)},
+	} {
+		require.NoError(t, db.ImportDocument(ctx, document, nil, false))
+	}
+
+	list := func(args ...string) []string {
+		var summaries []documentSummary
+
+		out := captureStdout(t, func() error { return adminList(ctx, db, args) })
+		require.NoError(t, json.Unmarshal([]byte(out), &summaries))
+
+		ids := []string{}
+
+		for _, summary := range summaries {
+			ids = append(ids, summary.ID)
+		}
+
+		return ids
+	}
+
+	require.Equal(t, []string{"aaaaaaaa", "aaaabbbb", "bbbbbbbb"}, list())
+	require.Equal(t, []string{"aaaaaaaa", "aaaabbbb"}, list("-prefix", "aaaa"))
+	require.Equal(t, []string{"aaaaaaaa", "aaaabbbb"}, list("-older-than", "1d"))
+	require.Equal(t, []string{"aaaabbbb", "bbbbbbbb"}, list("-newer-than", "5d"))
+	require.Equal(t, []string{"aaaabbbb"}, list("-older-than", "1d", "-newer-than", "5d"))
+	require.Equal(t, []string{"aaaabbbb", "bbbbbbbb"}, list("-min-size", "10"))
+	require.Equal(t, []string{"aaaaaaaa", "aaaabbbb"}, list("-max-size", "20"))
+	require.Equal(t, []string{"aaaaaaaa"}, list("-limit", "1"))
+
+	// Documents are listed without their content, but with how large it is
+	var summaries []map[string]interface{}
+
+	out := captureStdout(t, func() error { return adminList(ctx, db, []string{"-prefix", "bbbb"}) })
+	require.NoError(t, json.Unmarshal([]byte(out), &summaries))
+	require.NotContains(t, summaries[0], "content")
+	require.EqualValues(t, len("new, and the largest of them all"), summaries[0]["size"])
+
+	require.Error(t, adminList(ctx, db, []string{"-older-than", "soon"}))
+}
+
+func TestAdminPurge(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDatabase(t)
+	now := time.Now().UTC()
+
+	documents := config.Config.Documents
+	config.Config.Documents = []string{"about=about.md", "rules"}
+
+	t.Cleanup(func() { config.Config.Documents = documents })
+
+	for _, document := range []database.Document{
+		{ID: "aaaaaaaa", Content: "old", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "bbbbbbbb", Content: "new", CreatedAt: now.Add(-time.Hour)},
+		{ID: "about", Content: "custom", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+		{ID: "rules", Content: "custom", CreatedAt: now.Add(-10 * 24 * time.Hour)},
+	} {
+		require.NoError(t, db.ImportDocument(ctx, document, nil, false))
+	}
+
+	out := captureStdout(t, func() error { return adminPurge(ctx, db, []string{"-dry-run", "-older-than", "1d"}) })
+	require.JSONEq(t, `{"matched": 1, "size": 3}`, out)
+
+	// Custom documents are kept, however old they are
+	out = captureStdout(t, func() error { return adminPurge(ctx, db, []string{"-older-than", "1d"}) })
+	require.JSONEq(t, `{"deleted": 1}`, out)
+
+	for _, id := range []string{"bbbbbbbb", "about", "rules"} {
+		_, err := db.GetDocument(ctx, id)
+		require.NoError(t, err, id)
+	}
+
+	_, err := db.GetDocument(ctx, "aaaaaaaa")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.Error(t, adminPurge(ctx, db, nil))
+}
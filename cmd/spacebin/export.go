@@ -0,0 +1,502 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"golang.org/x/exp/slices"
+)
+
+// exportRecord is how a document is written to an export, with everything
+// needed to import it as it was.
+type exportRecord struct {
+	database.Document
+
+	// Token hashes are left out of documents' JSON everywhere else
+	DeleteTokenHash string `json:"delete_token_hash"`
+	EditTokenHash   string `json:"edit_token_hash"`
+
+	Revisions []database.Revision `json:"revisions"`
+
+	// "base64" if the content of the document, its files and its revisions is
+	// base64 encoded, because some of it isn't valid UTF-8
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// contents returns pointers to every piece of content in a record.
+func (r *exportRecord) contents() []*string {
+	contents := []*string{&r.Content}
+
+	for i := range r.Files {
+		contents = append(contents, &r.Files[i].Content)
+	}
+
+	for i := range r.Revisions {
+		contents = append(contents, &r.Revisions[i].Content)
+	}
+
+	return contents
+}
+
+// encode base64 encodes a record's content if JSON can't hold all of it.
+func (r *exportRecord) encode() {
+	contents := r.contents()
+
+	if !slices.ContainsFunc(contents, func(content *string) bool { return !utf8.ValidString(*content) }) {
+		return
+	}
+
+	for _, content := range contents {
+		*content = base64.StdEncoding.EncodeToString([]byte(*content))
+	}
+
+	r.Encoding = "base64"
+}
+
+// decode reverses encode.
+func (r *exportRecord) decode() error {
+	switch r.Encoding {
+	case "":
+		return nil
+	case "base64":
+	default:
+		return fmt.Errorf("document %s has unknown encoding %q", r.ID, r.Encoding)
+	}
+
+	for _, content := range r.contents() {
+		decoded, err := base64.StdEncoding.DecodeString(*content)
+
+		if err != nil {
+			return fmt.Errorf("document %s: %w", r.ID, err)
+		}
+
+		*content = string(decoded)
+	}
+
+	r.Encoding = ""
+	return nil
+}
+
+// exportWriter writes documents to an export.
+type exportWriter interface {
+	Write(record exportRecord) error
+	Close() error
+}
+
+// ndjsonWriter writes an export as one JSON document per line.
+type ndjsonWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func newNDJSONWriter(w io.Writer) *ndjsonWriter {
+	bw := bufio.NewWriter(w)
+	return &ndjsonWriter{bw, json.NewEncoder(bw)}
+}
+
+func (n *ndjsonWriter) Write(record exportRecord) error {
+	return n.enc.Encode(record)
+}
+
+func (n *ndjsonWriter) Close() error {
+	return n.w.Flush()
+}
+
+// tarWriter writes an export as a gzipped tarball, with a JSON file for every
+// document.
+type tarWriter struct {
+	gz *gzip.Writer
+	tw *tar.Writer
+}
+
+func newTarWriter(w io.Writer) *tarWriter {
+	gz := gzip.NewWriter(w)
+	return &tarWriter{gz, tar.NewWriter(gz)}
+}
+
+func (t *tarWriter) Write(record exportRecord) error {
+	content, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	if err := t.tw.WriteHeader(&tar.Header{
+		Typeflag: tar.TypeReg,
+		Name:     record.ID + ".json",
+		Mode:     0o644,
+		Size:     int64(len(content)),
+		ModTime:  record.UpdatedAt,
+	}); err != nil {
+		return err
+	}
+
+	_, err = t.tw.Write(content)
+	return err
+}
+
+func (t *tarWriter) Close() error {
+	if err := t.tw.Close(); err != nil {
+		return err
+	}
+
+	return t.gz.Close()
+}
+
+// exportReader reads documents from an export, returning io.EOF after the last.
+type exportReader interface {
+	Read() (exportRecord, error)
+}
+
+type ndjsonReader struct {
+	dec *json.Decoder
+}
+
+func (n *ndjsonReader) Read() (record exportRecord, err error) {
+	err = n.dec.Decode(&record)
+	return record, err
+}
+
+type tarReader struct {
+	tr *tar.Reader
+}
+
+func (t *tarReader) Read() (record exportRecord, err error) {
+	for {
+		header, err := t.tr.Next()
+
+		if err != nil {
+			return record, err
+		}
+
+		// Anything else someone added to the archive is skipped
+		if header.Typeflag != tar.TypeReg || !strings.HasSuffix(header.Name, ".json") {
+			continue
+		}
+
+		if err := json.NewDecoder(t.tr).Decode(&record); err != nil {
+			return record, fmt.Errorf("%s: %w", header.Name, err)
+		}
+
+		return record, nil
+	}
+}
+
+// newExportReader reads an export written in either format, telling them apart
+// by whether it's gzipped.
+func newExportReader(r io.Reader) (exportReader, error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(2)
+
+	if len(magic) < 2 || magic[0] != 0x1f || magic[1] != 0x8b {
+		return &ndjsonReader{json.NewDecoder(br)}, nil
+	}
+
+	gz, err := gzip.NewReader(br)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &tarReader{tar.NewReader(gz)}, nil
+}
+
+// progress reports how far an export or import has got to standard error, at
+// most once a second.
+type progress struct {
+	verb  string
+	total int // 0 if it isn't known
+	done  int
+	quiet bool
+	last  time.Time
+}
+
+func (p *progress) add() {
+	p.done++
+
+	if time.Since(p.last) >= time.Second {
+		p.report()
+	}
+}
+
+func (p *progress) report() {
+	p.last = time.Now()
+
+	if p.quiet {
+		return
+	}
+
+	if p.total > 0 {
+		fmt.Fprintf(os.Stderr, "%s %d/%d documents\n", p.verb, p.done, p.total)
+	} else {
+		fmt.Fprintf(os.Stderr, "%s %d documents\n", p.verb, p.done)
+	}
+}
+
+// export writes every document in the database, with its revisions and files,
+// to an archive that import can load into any other database.
+func export(args []string) {
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	output := flags.String("o", "-", "file to write the export to (- for standard output)")
+	format := flags.String("format", "", "ndjson or tar.gz (picked from the output file's name if blank)")
+	quiet := flags.Bool("quiet", false, "don't report progress")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spacebin export [flags]")
+		flags.PrintDefaults()
+	}
+
+	flags.Parse(args)
+
+	if *format == "" {
+		*format = "ndjson"
+
+		if strings.HasSuffix(*output, ".tar.gz") || strings.HasSuffix(*output, ".tgz") {
+			*format = "tar.gz"
+		}
+	}
+
+	if flags.NArg() != 0 || !slices.Contains([]string{"ndjson", "tar.gz"}, *format) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	loadConfig()
+	db, err := openDatabase(config.Config.ConnectionURI, config.Config.ContentStore)
+
+	if err != nil {
+		fatal(fmt.Errorf("could not connect to database: %w", err))
+	}
+
+	defer db.Close()
+
+	out := os.Stdout
+
+	if *output != "-" {
+		if out, err = os.Create(*output); err != nil {
+			fatal(err)
+		}
+
+	}
+
+	var w exportWriter = newNDJSONWriter(out)
+
+	if *format == "tar.gz" {
+		w = newTarWriter(out)
+	}
+
+	p := &progress{verb: "Exported", quiet: *quiet}
+
+	if err := exportDocuments(context.Background(), db, w, p); err != nil {
+		fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		fatal(err)
+	}
+
+	if out != os.Stdout {
+		if err := out.Close(); err != nil {
+			fatal(err)
+		}
+	}
+
+	p.report()
+}
+
+func exportDocuments(ctx context.Context, db database.Database, w exportWriter, p *progress) error {
+	// Every document is listed up front, without its content or files. Then each
+	// is read in full, with its revisions, and written out before the next, so
+	// only one document's content is held in memory at a time
+	documents, err := db.ListDocuments(ctx, database.DocumentFilter{})
+
+	if err != nil {
+		return err
+	}
+
+	p.total = len(documents)
+
+	for _, d := range documents {
+		// Reading a burn after reading document here doesn't burn it
+		document, err := db.GetDocument(ctx, d.ID)
+
+		// It may have been deleted since it was listed
+		if errors.Is(err, sql.ErrNoRows) {
+			p.total--
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("document %s: %w", d.ID, err)
+		}
+
+		revisions, err := db.GetRevisions(ctx, d.ID)
+
+		if err != nil {
+			return fmt.Errorf("document %s: %w", d.ID, err)
+		}
+
+		record := exportRecord{Document: document, DeleteTokenHash: document.DeleteTokenHash,
+			EditTokenHash: document.EditTokenHash, Revisions: revisions}
+		record.encode()
+
+		if err := w.Write(record); err != nil {
+			return err
+		}
+
+		p.add()
+	}
+
+	return nil
+}
+
+// importResult counts what happened to the documents in an import.
+type importResult struct {
+	Imported int `json:"imported"` // Including those that replaced existing documents
+	Skipped  int `json:"skipped"`
+}
+
+// importArchive loads an archive written by export into the database.
+func importArchive(args []string) {
+	flags := flag.NewFlagSet("import", flag.ExitOnError)
+	onConflict := flags.String("on-conflict", "skip", "what to do with documents that already exist: skip or overwrite")
+	quiet := flags.Bool("quiet", false, "don't report progress")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spacebin import [flags] [file]")
+		flags.PrintDefaults()
+	}
+
+	// Flags may come before or after the file
+	flags.Parse(args)
+	file := flags.Arg(0)
+
+	if flags.NArg() > 0 {
+		flags.Parse(flags.Args()[1:])
+	}
+
+	if flags.NArg() != 0 || !slices.Contains([]string{"skip", "overwrite"}, *onConflict) {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	in := os.Stdin
+
+	if file != "" && file != "-" {
+		var err error
+
+		if in, err = os.Open(file); err != nil {
+			fatal(err)
+		}
+
+		defer in.Close()
+	}
+
+	r, err := newExportReader(in)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	loadConfig()
+	db, err := openDatabase(config.Config.ConnectionURI, config.Config.ContentStore)
+
+	if err != nil {
+		fatal(fmt.Errorf("could not connect to database: %w", err))
+	}
+
+	// Documents changed here mustn't be left in the cache the server reads from
+	if db, err = cacheDatabase(db); err != nil {
+		fatal(fmt.Errorf("could not set up document cache: %w", err))
+	}
+
+	defer db.Close()
+
+	p := &progress{verb: "Imported", quiet: *quiet}
+	result, err := importDocuments(context.Background(), db, r, *onConflict == "overwrite", p)
+	p.report()
+
+	if err != nil {
+		fatal(err)
+	}
+
+	printJSON(result)
+}
+
+func importDocuments(ctx context.Context, db database.Database, r exportReader, overwrite bool,
+	p *progress) (importResult, error) {
+	var result importResult
+
+	for {
+		record, err := r.Read()
+
+		if errors.Is(err, io.EOF) {
+			return result, nil
+		}
+
+		if err != nil {
+			return result, err
+		}
+
+		if err := record.decode(); err != nil {
+			return result, err
+		}
+
+		document := record.Document
+		document.DeleteTokenHash, document.EditTokenHash = record.DeleteTokenHash, record.EditTokenHash
+
+		// Hashes are worked out again from the content, rather than trusted
+		document.ContentHash = ""
+
+		for i := range document.Files {
+			document.Files[i].ContentHash = ""
+		}
+
+		for i := range record.Revisions {
+			record.Revisions[i].ContentHash = ""
+		}
+
+		err = db.ImportDocument(ctx, document, record.Revisions, overwrite)
+
+		if errors.Is(err, database.ErrDocumentExists) {
+			result.Skipped++
+		} else if err != nil {
+			return result, fmt.Errorf("document %s: %w", document.ID, err)
+		} else {
+			result.Imported++
+		}
+
+		p.add()
+	}
+}
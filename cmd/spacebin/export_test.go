@@ -0,0 +1,271 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestDatabase creates an empty SQLite database.
+func newTestDatabase(t *testing.T) database.Database {
+	db, err := database.NewSQLite(&url.URL{Host: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	require.NoError(t, db.Migrate(context.Background()))
+
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+// image is content that isn't valid UTF-8, so has to be base64 encoded.
+const image = "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff\xfe"
+
+// seedDocuments creates a text document with two revisions, a document of
+// files that aren't text, and a burn after reading document.
+func seedDocuments(t *testing.T, db database.Database) {
+	ctx := context.Background()
+	expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+
+	require.NoError(t, db.CreateDocument(ctx, database.Document{
+		ID:              "aaaaaaaa",
+		Content:         "first",
+		ExpiresAt:       &expiresAt,
+		DeleteTokenHash: "delete",
+		EditTokenHash:   "edit",
+		Language:        "plaintext",
+		ContentType:     "text/plain; charset=utf-8",
+	}))
+
+	_, err := db.UpdateDocument(ctx, database.Document{ID: "aaaaaaaa", Content: "second"})
+	require.NoError(t, err)
+
+	require.NoError(t, db.CreateDocument(ctx, database.Document{
+		ID:          "bbbbbbbb",
+		Content:     image,
+		ContentType: "image/png",
+		Files: []database.File{
+			{Name: "image.png", ContentType: "image/png", Content: image},
+			{Name: "notes.txt", Language: "plaintext", ContentType: "text/plain; charset=utf-8", Content: "notes"},
+		},
+	}))
+
+	require.NoError(t, db.CreateDocument(ctx, database.Document{ID: "cccccccc", Content: "secret", BurnAfterRead: true}))
+}
+
+// exportTo exports every document in db in a format.
+func exportTo(t *testing.T, db database.Database, format string) *bytes.Buffer {
+	var buf bytes.Buffer
+	var w exportWriter = newNDJSONWriter(&buf)
+
+	if format == "tar.gz" {
+		w = newTarWriter(&buf)
+	}
+
+	require.NoError(t, exportDocuments(context.Background(), db, w, &progress{quiet: true}))
+	require.NoError(t, w.Close())
+
+	return &buf
+}
+
+// importFrom imports an export into db.
+func importFrom(t *testing.T, db database.Database, export []byte, overwrite bool) importResult {
+	r, err := newExportReader(bytes.NewReader(export))
+	require.NoError(t, err)
+
+	result, err := importDocuments(context.Background(), db, r, overwrite, &progress{quiet: true})
+	require.NoError(t, err)
+
+	return result
+}
+
+func TestExportImport(t *testing.T) {
+	for _, format := range []string{"ndjson", "tar.gz"} {
+		t.Run(format, func(t *testing.T) {
+			ctx := context.Background()
+			source, target := newTestDatabase(t), newTestDatabase(t)
+			seedDocuments(t, source)
+
+			export := exportTo(t, source, format).Bytes()
+			require.Equal(t, importResult{Imported: 3}, importFrom(t, target, export, false))
+
+			for _, id := range []string{"aaaaaaaa", "bbbbbbbb", "cccccccc"} {
+				want, err := source.GetDocument(ctx, id)
+				require.NoError(t, err)
+
+				got, err := target.GetDocument(ctx, id)
+				require.NoError(t, err)
+
+				// Timestamps are compared on their own, as they may come back in another zone
+				require.WithinDuration(t, want.CreatedAt, got.CreatedAt, time.Second)
+				require.WithinDuration(t, want.UpdatedAt, got.UpdatedAt, time.Second)
+
+				if want.ExpiresAt != nil {
+					require.NotNil(t, got.ExpiresAt)
+					require.WithinDuration(t, *want.ExpiresAt, *got.ExpiresAt, time.Second)
+				}
+
+				want.CreatedAt, want.UpdatedAt, want.ExpiresAt = time.Time{}, time.Time{}, nil
+				got.CreatedAt, got.UpdatedAt, got.ExpiresAt = time.Time{}, time.Time{}, nil
+				require.Equal(t, want, got)
+
+				wantRevisions, err := source.GetRevisions(ctx, id)
+				require.NoError(t, err)
+
+				gotRevisions, err := target.GetRevisions(ctx, id)
+				require.NoError(t, err)
+				require.Len(t, gotRevisions, len(wantRevisions))
+
+				for i := range wantRevisions {
+					require.Equal(t, wantRevisions[i].Content, gotRevisions[i].Content)
+				}
+			}
+		})
+	}
+}
+
+func TestExportEncoding(t *testing.T) {
+	db := newTestDatabase(t)
+	seedDocuments(t, db)
+
+	dec := json.NewDecoder(exportTo(t, db, "ndjson"))
+	encodings := map[string]string{}
+
+	for {
+		var record exportRecord
+		err := dec.Decode(&record)
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+		encodings[record.ID] = record.Encoding
+
+		// Token hashes are kept, even though documents' JSON leaves them out
+		if record.ID == "aaaaaaaa" {
+			require.Equal(t, "delete", record.DeleteTokenHash)
+			require.Equal(t, "edit", record.EditTokenHash)
+			require.Len(t, record.Revisions, 2)
+		}
+	}
+
+	// Only documents with content that isn't valid UTF-8 are base64 encoded
+	require.Equal(t, map[string]string{"aaaaaaaa": "", "bbbbbbbb": "base64", "cccccccc": ""}, encodings)
+
+	record := exportRecord{Document: database.Document{ID: "bbbbbbbb", Content: image,
+		Files: []database.File{{Content: image}, {Content: "notes"}}}}
+	record.encode()
+	require.NotEqual(t, image, record.Content)
+
+	require.NoError(t, record.decode())
+	require.Equal(t, image, record.Content)
+	require.Equal(t, "notes", record.Files[1].Content)
+
+	record.Encoding = "rot13"
+	require.Error(t, record.decode())
+}
+
+func TestImportConflicts(t *testing.T) {
+	ctx := context.Background()
+	source, target := newTestDatabase(t), newTestDatabase(t)
+	seedDocuments(t, source)
+
+	export := exportTo(t, source, "ndjson").Bytes()
+	require.Equal(t, importResult{Imported: 3}, importFrom(t, target, export, false))
+
+	_, err := target.UpdateDocument(ctx, database.Document{ID: "aaaaaaaa", Content: "changed"})
+	require.NoError(t, err)
+
+	// Documents that already exist are skipped, and left as they are
+	require.Equal(t, importResult{Skipped: 3}, importFrom(t, target, export, false))
+
+	document, err := target.GetDocument(ctx, "aaaaaaaa")
+	require.NoError(t, err)
+	require.Equal(t, "changed", document.Content)
+
+	// Unless they're overwritten, revisions and all
+	require.Equal(t, importResult{Imported: 3}, importFrom(t, target, export, true))
+
+	document, err = target.GetDocument(ctx, "aaaaaaaa")
+	require.NoError(t, err)
+	require.Equal(t, "second", document.Content)
+
+	revisions, err := target.GetRevisions(ctx, "aaaaaaaa")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+}
+
+func TestNewExportReader(t *testing.T) {
+	// Anything that isn't gzipped is read as NDJSON, even if it's empty
+	r, err := newExportReader(strings.NewReader(""))
+	require.NoError(t, err)
+	require.IsType(t, &ndjsonReader{}, r)
+
+	_, err = r.Read()
+	require.ErrorIs(t, err, io.EOF)
+
+	r, err = newExportReader(strings.NewReader(`{"id":"aaaaaaaa","content":"hello"}` + "\n"))
+	require.NoError(t, err)
+
+	record, err := r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "hello", record.Content)
+
+	// Tarballs can have other files in them, which are skipped
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+
+	for name, content := range map[string]string{"README": "not a document", "aaaaaaaa.json": `{"id":"aaaaaaaa"}`} {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Typeflag: tar.TypeReg, Name: name, Mode: 0o644,
+			Size: int64(len(content))}))
+		_, err := tw.Write([]byte(content))
+		require.NoError(t, err)
+	}
+
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+
+	r, err = newExportReader(&buf)
+	require.NoError(t, err)
+	require.IsType(t, &tarReader{}, r)
+
+	record, err = r.Read()
+	require.NoError(t, err)
+	require.Equal(t, "aaaaaaaa", record.ID)
+
+	_, err = r.Read()
+	require.ErrorIs(t, err, io.EOF)
+
+	// Broken gzip headers are reported straight away
+	_, err = newExportReader(bytes.NewReader([]byte{0x1f, 0x8b, 0x00}))
+	require.Error(t, err)
+}
@@ -0,0 +1,126 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+)
+
+// getOptions are the flags and document get is run with.
+type getOptions struct {
+	server string
+	format string
+	id     string
+}
+
+// parseGet parses get's arguments, printing its usage to output if they're wrong.
+func parseGet(args []string, output io.Writer) (getOptions, error) {
+	var opts getOptions
+
+	flags := flag.NewFlagSet("get", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&opts.server, "server", defaultServer(), "URL of the instance to fetch from")
+	flags.StringVar(&opts.format, "format", "raw", "what to print: raw content, or json")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spacebin get [flags] <id>")
+		flags.PrintDefaults()
+	}
+
+	id, err := parseArgs(flags, args)
+
+	if err != nil {
+		return opts, err
+	}
+
+	if id == "" || (opts.format != "raw" && opts.format != "json") {
+		flags.Usage()
+		return opts, errUsage
+	}
+
+	server, id := documentID(id)
+
+	// A document's URL says which instance it's on, unless another is picked
+	serverSet := false
+
+	flags.Visit(func(f *flag.Flag) {
+		serverSet = serverSet || f.Name == "server"
+	})
+
+	if server != "" && !serverSet {
+		opts.server = server
+	}
+
+	opts.id = id
+	return opts, nil
+}
+
+// documentID takes a document's ID, and the URL of the instance it's on, from
+// its URL, as paste prints it, or from a link to it with an extension picking
+// its language or to its raw content. IDs are returned as they are, with no
+// instance.
+func documentID(arg string) (server, id string) {
+	if u, err := url.Parse(arg); err == nil && u.Scheme != "" {
+		server = (&url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}).String()
+		arg = u.Path
+	}
+
+	arg = strings.TrimSuffix(strings.TrimSuffix(arg, "/"), "/raw")
+	id, _, _ = strings.Cut(path.Base(arg), ".")
+	return server, id
+}
+
+// get prints a document from an instance.
+func get(args []string) {
+	opts, err := parseGet(args, os.Stderr)
+
+	if err != nil {
+		exitUsage(err)
+	}
+
+	c, err := newClient(opts.server)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	switch opts.format {
+	case "raw":
+		content, err := c.GetRaw(context.Background(), opts.id)
+
+		if err != nil {
+			fatal(err)
+		}
+
+		fmt.Print(content)
+	case "json":
+		document, err := c.Get(context.Background(), opts.id)
+
+		if err != nil {
+			fatal(err)
+		}
+
+		printJSON(document)
+	}
+}
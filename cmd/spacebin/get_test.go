@@ -0,0 +1,80 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGet(t *testing.T) {
+	t.Setenv("SPACEBIN_URL", "")
+
+	for _, args := range [][]string{
+		{"-format", "json", "abcd1234"},
+		{"abcd1234", "-format", "json"},
+		{"-server", "https://spaceb.in", "abcd1234", "-format=json"},
+	} {
+		opts, err := parseGet(args, io.Discard)
+		require.NoError(t, err, args)
+		require.Equal(t, getOptions{server: "https://spaceb.in", format: "json", id: "abcd1234"}, opts, args)
+	}
+
+	// Documents are fetched from the instance in their URL, unless -server is given
+	opts, err := parseGet([]string{"https://paste.example.com/abcd1234"}, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, getOptions{server: "https://paste.example.com", format: "raw", id: "abcd1234"}, opts)
+
+	opts, err = parseGet([]string{"-server", "https://spaceb.in", "https://paste.example.com/abcd1234"}, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, getOptions{server: "https://spaceb.in", format: "raw", id: "abcd1234"}, opts)
+
+	for _, args := range [][]string{
+		nil,
+		{"-format", "json"},
+		{"abcd1234", "efgh5678"},
+		{"-format", "xml", "abcd1234"},
+	} {
+		_, err := parseGet(args, io.Discard)
+		require.Error(t, err, args)
+	}
+}
+
+func TestDocumentID(t *testing.T) {
+	for arg, id := range map[string]string{
+		"https://spaceb.in/abcd1234":          "abcd1234",
+		"https://spaceb.in/abcd1234/":         "abcd1234",
+		"https://spaceb.in/abcd1234.go":       "abcd1234",
+		"https://spaceb.in/abcd1234?theme=x":  "abcd1234",
+		"https://spaceb.in/abcd1234.md#L1-L3": "abcd1234",
+		"https://spaceb.in/abcd1234/raw":      "abcd1234",
+		"https://spaceb.in/api/abcd1234/raw/": "abcd1234",
+	} {
+		server, got := documentID(arg)
+		require.Equal(t, id, got, arg)
+		require.Equal(t, "https://spaceb.in", server, arg)
+	}
+
+	// Without a scheme, there's no telling what's an instance
+	for _, arg := range []string{"abcd1234", "spaceb.in/abcd1234.py"} {
+		server, got := documentID(arg)
+		require.Equal(t, "abcd1234", got, arg)
+		require.Empty(t, server, arg)
+	}
+}
@@ -0,0 +1,108 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/rs/zerolog/log"
+)
+
+// migrate applies, reverts or lists database migrations.
+func migrate(args []string) {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations to revert with down")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spacebin migrate [-steps n] up|down|status")
+		flags.PrintDefaults()
+	}
+
+	// Flags may come before or after the action
+	flags.Parse(args)
+	action := flags.Arg(0)
+
+	if flags.NArg() > 0 {
+		flags.Parse(flags.Args()[1:])
+	}
+
+	if action == "" || flags.NArg() != 0 || *steps < 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	// Migrations only change the database, so the content store isn't needed
+	db, err := openDatabase(config.Config.ConnectionURI, "")
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Could not connect to database")
+	}
+
+	defer db.Close()
+
+	ctx := context.Background()
+
+	switch action {
+	case "up":
+		err = db.Migrate(ctx)
+	case "down":
+		err = db.MigrateDown(ctx, *steps)
+	case "status":
+		err = printMigrationStatus(ctx, db)
+	default:
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed migrations")
+	}
+}
+
+func printMigrationStatus(ctx context.Context, db database.Database) error {
+	status, err := db.MigrationStatus(ctx)
+
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+
+	for _, s := range status {
+		appliedAt := "pending"
+
+		if s.AppliedAt != nil {
+			appliedAt = s.AppliedAt.Format(time.RFC3339)
+		}
+
+		fmt.Fprintf(w, "%04d\t%s\t%s\n", s.Version, s.Name, appliedAt)
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,83 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+)
+
+// passwd prints a line for the users file, with a user's password hashed. The
+// password is read from standard input, so it isn't left in shell history.
+func passwd(args []string) {
+	flags := flag.NewFlagSet("passwd", flag.ExitOnError)
+	permission := flags.String("permission", "read", "what the user can do: read, or create documents")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spacebin passwd [flags] <name> < password")
+		flags.PrintDefaults()
+	}
+
+	// Flags may come before or after the name
+	flags.Parse(args)
+	name := flags.Arg(0)
+
+	if flags.NArg() > 0 {
+		flags.Parse(flags.Args()[1:])
+	}
+
+	p, err := util.ParsePermission(*permission)
+
+	if flags.NArg() != 0 || name == "" || err != nil {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	password, err := bufio.NewReader(os.Stdin).ReadString('\n')
+
+	if err != nil && !errors.Is(err, io.EOF) {
+		fatal(err)
+	}
+
+	password = strings.TrimRight(password, "\r\n")
+
+	if password == "" {
+		fatal(errors.New("no password given on standard input"))
+	}
+
+	hash, err := util.HashPassword(password)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	user := util.User{Name: name, Hash: hash, Permission: p}
+
+	// Check the line can be read back, e.g. that the name has no colons
+	if err := util.NewUsers().Add(user); err != nil {
+		fatal(err)
+	}
+
+	fmt.Printf("%s:%s:%s\n", user.Name, user.Hash, user.Permission)
+}
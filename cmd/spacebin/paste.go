@@ -0,0 +1,209 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/client"
+	"golang.org/x/exp/slices"
+)
+
+// errUsage is returned when a command's arguments don't make sense, once its
+// usage has been printed.
+var errUsage = errors.New("invalid usage")
+
+// parseArgs parses flags that may come before or after a command's single
+// argument, which is returned. It's blank if there isn't one.
+func parseArgs(flags *flag.FlagSet, args []string) (string, error) {
+	if err := flags.Parse(args); err != nil {
+		return "", err
+	}
+
+	arg := flags.Arg(0)
+
+	if flags.NArg() > 0 {
+		if err := flags.Parse(flags.Args()[1:]); err != nil {
+			return "", err
+		}
+	}
+
+	if flags.NArg() != 0 {
+		flags.Usage()
+		return "", errUsage
+	}
+
+	return arg, nil
+}
+
+// exitUsage exits after a command's arguments failed to parse, as
+// flag.ExitOnError would have.
+func exitUsage(err error) {
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(0)
+	}
+
+	os.Exit(2)
+}
+
+// pasteOptions are the flags and file paste is run with.
+type pasteOptions struct {
+	server   string
+	language string
+	expires  time.Duration
+	burn     bool
+	format   string
+	file     string
+}
+
+// parsePaste parses paste's arguments, printing its usage to output if they're
+// wrong.
+func parsePaste(args []string, output io.Writer) (pasteOptions, error) {
+	var opts pasteOptions
+
+	flags := flag.NewFlagSet("paste", flag.ContinueOnError)
+	flags.SetOutput(output)
+	flags.StringVar(&opts.server, "server", defaultServer(), "URL of the instance to upload to")
+	flags.StringVar(&opts.language, "language", "", "language to highlight the document as, detected if blank")
+	flags.DurationVar(&opts.expires, "expires", 0, "how long until the document expires, like 24h (0 for the instance's default)")
+	flags.BoolVar(&opts.burn, "burn", false, "delete the document once it's first viewed")
+	flags.StringVar(&opts.format, "format", "url", "what to print: url, id, or json, which includes the document's tokens")
+
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: spacebin paste [flags] [file]")
+		flags.PrintDefaults()
+	}
+
+	file, err := parseArgs(flags, args)
+
+	if err != nil {
+		return opts, err
+	}
+
+	if opts.expires < 0 || !slices.Contains([]string{"url", "id", "json"}, opts.format) {
+		flags.Usage()
+		return opts, errUsage
+	}
+
+	opts.file = file
+	return opts, nil
+}
+
+// paste uploads a file, or standard input, as a new document.
+func paste(args []string) {
+	opts, err := parsePaste(args, os.Stderr)
+
+	if err != nil {
+		exitUsage(err)
+	}
+
+	c, err := newClient(opts.server)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	content, err := readPaste(opts.file)
+
+	if err != nil {
+		fatal(err)
+	}
+
+	if !util.IsText(util.DetectContentType(content)) {
+		fatal(fmt.Errorf("%s isn't text", pasteName(opts.file)))
+	}
+
+	// Files' names say more about their language than their content does
+	if opts.language == "" && opts.file != "" {
+		opts.language = util.DetectFileLanguage(filepath.Base(opts.file), content)
+	}
+
+	res, err := c.Create(context.Background(), client.CreateRequest{
+		Content:       content,
+		ExpiresIn:     int64(opts.expires.Seconds()),
+		BurnAfterRead: opts.burn,
+		Language:      opts.language,
+	})
+
+	if err != nil {
+		fatal(err)
+	}
+
+	switch opts.format {
+	case "url":
+		fmt.Println(c.BaseURL + "/" + res.ID)
+	case "id":
+		fmt.Println(res.ID)
+	case "json":
+		printJSON(res)
+	}
+}
+
+// readPaste reads the file to be pasted, or standard input if file is blank or "-".
+func readPaste(file string) (string, error) {
+	r := io.Reader(os.Stdin)
+
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+
+		if err != nil {
+			return "", err
+		}
+
+		defer f.Close()
+		r = f
+	}
+
+	content, err := io.ReadAll(r)
+	return string(content), err
+}
+
+// pasteName names the file being pasted in messages.
+func pasteName(file string) string {
+	if file == "" || file == "-" {
+		return "standard input"
+	}
+
+	return file
+}
+
+// printJSON prints v to standard output as indented JSON.
+func printJSON(v any) {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+
+	if err := enc.Encode(v); err != nil {
+		fatal(err)
+	}
+}
+
+// fatal reports an error from a command whose output is usually piped
+// elsewhere, like paste or admin, and exits. Errors aren't logged to standard
+// output like the server's are.
+func fatal(err error) {
+	fmt.Fprintln(os.Stderr, "Error:", err)
+	os.Exit(1)
+}
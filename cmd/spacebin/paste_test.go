@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"flag"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePaste(t *testing.T) {
+	t.Setenv("SPACEBIN_URL", "")
+
+	opts, err := parsePaste(nil, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, pasteOptions{server: "https://spaceb.in", format: "url"}, opts)
+
+	// Flags may come before or after the file
+	for _, args := range [][]string{
+		{"-burn", "-expires", "24h", "-format", "json", "main.go"},
+		{"main.go", "-burn", "-expires", "24h", "-format", "json"},
+		{"-burn", "main.go", "-expires=24h", "-format=json"},
+	} {
+		opts, err := parsePaste(args, io.Discard)
+		require.NoError(t, err, args)
+		require.Equal(t, pasteOptions{server: "https://spaceb.in", expires: 24 * time.Hour, burn: true,
+			format: "json", file: "main.go"}, opts, args)
+	}
+
+	t.Setenv("SPACEBIN_URL", "https://paste.example.com")
+
+	opts, err = parsePaste([]string{"-language", "go", "-"}, io.Discard)
+	require.NoError(t, err)
+	require.Equal(t, pasteOptions{server: "https://paste.example.com", language: "go", format: "url", file: "-"}, opts)
+
+	for _, args := range [][]string{
+		{"a.txt", "b.txt"},
+		{"-format", "xml"},
+		{"-expires", "-1h"},
+		{"-expires", "soon"},
+		{"-unknown"},
+	} {
+		_, err := parsePaste(args, io.Discard)
+		require.Error(t, err, args)
+	}
+
+	_, err = parsePaste([]string{"-h"}, io.Discard)
+	require.ErrorIs(t, err, flag.ErrHelp)
+}
@@ -0,0 +1,199 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// reapInterval is how often expired documents are purged from the database
+	reapInterval = 5 * time.Minute
+
+	// documentsSyncInterval is how often custom document files are checked for changes
+	documentsSyncInterval = 10 * time.Second
+)
+
+// serve runs the Spacebin server until it's told to shut down.
+func serve() {
+	db, err := openDatabase(config.Config.ConnectionURI, config.Config.ContentStore)
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Could not connect to database")
+	}
+
+	if !util.IsTheme(config.Config.Theme) {
+		log.Fatal().
+			Str("theme", config.Config.Theme).
+			Msg("Unknown highlighting theme")
+	}
+
+	// Cache recently read documents, if enabled
+	db, err = cacheDatabase(db)
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Could not set up document cache")
+	}
+
+	// Perform migrations
+	if err := db.Migrate(context.Background()); err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Failed migrations; Could not update database schema.")
+	}
+
+	// Load custom documents from disk, and keep them up to date if enabled
+	documents, err := util.ParseDocumentsList(config.Config.Documents)
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Could not parse custom documents list")
+	}
+
+	documentSync := database.NewDocumentSync(db, documents)
+
+	if err := documentSync.Sync(context.Background()); err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Could not load custom documents")
+	}
+
+	if config.Config.WatchDocuments {
+		documentSync.Start(documentsSyncInterval)
+	}
+
+	// Periodically delete expired documents. Custom documents never expire
+	custom := make([]string, 0, len(documents))
+
+	for id := range documents {
+		custom = append(custom, id)
+	}
+
+	reaper := database.NewReaper(db, reapInterval, time.Duration(config.Config.ExpirationAge)*time.Hour, custom)
+	reaper.Start()
+
+	users, err := util.LoadUsers(config.Config.UsersFile)
+
+	if err != nil {
+		log.Fatal().
+			Err(err).
+			Msg("Could not load users file")
+	}
+
+	// Create a new server and register middleware, security headers, static files, and handlers
+	m := server.NewServer(&config.Config, db)
+	m.Users = users
+
+	m.MountMiddleware()
+	m.RegisterHeaders()
+
+	if !config.Config.Headless {
+		m.MountStatic()
+	}
+
+	m.MountHandlers()
+
+	// Create the server on the specified host and port
+	srv := &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", config.Config.Host, config.Config.Port),
+		Handler: m.Router,
+	}
+
+	// Graceful shutdown
+	srvCtx, srvStopCtx := context.WithCancel(context.Background())
+
+	// Watch for OS signals
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	go func() {
+		<-sig
+
+		shutdownCtx, shutdownCtxCancel := context.WithTimeout(srvCtx, 30*time.Second)
+		defer shutdownCtxCancel() // release srvCtx if we take too long to shut down
+
+		go func() {
+			<-shutdownCtx.Done()
+			if errors.Is(shutdownCtx.Err(), context.DeadlineExceeded) {
+				log.Warn().Msg("Graceful shutdown timed out... forcing regular exit.")
+			}
+		}()
+
+		// Gracefully shut down services
+		log.Info().Msg("Killing services")
+
+		// Web server
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed shutting HTTP listener down")
+		}
+
+		// Background workers
+		reaper.Stop()
+
+		if config.Config.WatchDocuments {
+			documentSync.Stop()
+		}
+
+		// Database
+		err := db.Close()
+
+		if err != nil {
+			log.Fatal().
+				Err(err).
+				Msg("Failed closing database connection")
+		}
+
+		srvStopCtx()
+	}()
+
+	log.Info().
+		Str("host", config.Config.Host).
+		Int("port", config.Config.Port).
+		Msg("Starting HTTP listener")
+
+	// Start the server
+	err = srv.ListenAndServe()
+
+	if err != nil && err != http.ErrServerClosed {
+		log.Fatal().
+			Err(err).
+			Msg("Failed to start HTTP listener")
+	}
+
+	<-srvCtx.Done()
+	log.Info().Msg("Successfully and cleanly shut down all Spirit services")
+}
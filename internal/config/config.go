@@ -27,20 +27,30 @@ type Cfg struct {
 	CompressionLevel int    `env:"COMPRESS_LEVEL" envDefault:"1" json:"compression_level"`
 	Ratelimiter      string `env:"RATELIMITER" envDefault:"200x5" json:"ratelimiter"` // Requests x Seconds
 	ConnectionURI    string `env:"CONNECTION_URI" json:"-"`
+	ContentStore     string `env:"CONTENT_STORE" envDefault:"" json:"-"`      // Where document content is kept, if not in the database
+	Cache            string `env:"CACHE" envDefault:"" json:"-"`              // "memory" or a redis:// URI to cache documents in (leave blank to disable)
+	CacheTTL         int    `env:"CACHE_TTL" envDefault:"300" json:"-"`       // in seconds
+	CacheEntries     int    `env:"CACHE_ENTRIES" envDefault:"1000" json:"-"`  // Most documents kept by the memory cache
+	CacheSize        int64  `env:"CACHE_SIZE" envDefault:"67108864" json:"-"` // Most bytes kept by the memory cache
 
 	// Web
-	Headless              bool   `env:"HEADLESS" envDefault:"false" json:"headless"`                                                                                                                                       // Enable website
-	Analytics             string `env:"ANALYTICS" envDefault:"" json:"analytics"`                                                                                                                                          // <script> tag for analytics (leave blank to disable)
-	Username              string `env:"USERNAME" envDefault:"" json:"username"`                                                                                                                                            // Basic Auth username. Required to enable Basic Auth
-	Password              string `env:"PASSWORD" envDefault:"" json:"password"`                                                                                                                                            // Basic Auth password. Required to enable Basic Auth
-	ContentSecurityPolicy string `env:"CSP" envDefault:"default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline';" json:"csp"` // Content Security Policy. Must be changed if you are using analytics.
+	Headless              bool   `env:"HEADLESS" envDefault:"false" json:"headless"`                                                                                                                                                             // Enable website
+	Analytics             string `env:"ANALYTICS" envDefault:"" json:"analytics"`                                                                                                                                                                // <script> tag for analytics (leave blank to disable)
+	Username              string `env:"USERNAME" envDefault:"" json:"-"`                                                                                                                                                                         // Basic Auth username of a single user who can create documents. Prefer USERS_FILE
+	Password              string `env:"PASSWORD" envDefault:"" json:"-"`                                                                                                                                                                         // Basic Auth password of that user
+	UsersFile             string `env:"USERS_FILE" envDefault:"" json:"-"`                                                                                                                                                                       // File of users let in with Basic Auth, with their password hashes and permissions
+	Theme                 string `env:"THEME" envDefault:"auto" json:"theme"`                                                                                                                                                                    // Theme documents are highlighted with, unless another is picked with ?theme=
+	HighlightCache        int    `env:"HIGHLIGHT_CACHE" envDefault:"128" json:"-"`                                                                                                                                                               // Number of highlighted documents to keep (0 to disable)
+	HighlightCacheSize    int64  `env:"HIGHLIGHT_CACHE_SIZE" envDefault:"33554432" json:"-"`                                                                                                                                                     // Most bytes of highlighted HTML to keep (0 for no limit)
+	ContentSecurityPolicy string `env:"CSP" envDefault:"default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:;" json:"csp"` // Content Security Policy. Must be changed if you are using analytics.
 
 	// Document
-	IDLength      int      `env:"ID_LENGTH" envDefault:"8" json:"id_length"`
-	IDType        string   `env:"ID_TYPE" envDefault:"key" json:"id_type"`
-	MaxSize       int      `env:"MAX_SIZE" envDefault:"400000" json:"max_size"`          // in bytes
-	ExpirationAge int64    `env:"EXPIRATION_AGE" envDefault:"720" json:"expiration_age"` // in hours
-	Documents     []string `env:"DOCUMENTS" envDefault:"" json:"documents"`
+	IDLength       int      `env:"ID_LENGTH" envDefault:"8" json:"id_length"`
+	IDType         string   `env:"ID_TYPE" envDefault:"key" json:"id_type"`
+	MaxSize        int      `env:"MAX_SIZE" envDefault:"400000" json:"max_size"`              // in bytes
+	ExpirationAge  int64    `env:"EXPIRATION_AGE" envDefault:"720" json:"expiration_age"`     // in hours
+	Documents      []string `env:"DOCUMENTS" envDefault:"" json:"documents"`                  // IDs of custom documents, optionally loaded from a file with id=path
+	WatchDocuments bool     `env:"WATCH_DOCUMENTS" envDefault:"false" json:"watch_documents"` // Reload custom documents when their files change
 }
 
 // Config is the loaded config object
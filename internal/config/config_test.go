@@ -39,8 +39,14 @@ func TestLoad(t *testing.T) {
 		IDType:                "key",
 		MaxSize:               400_000,
 		Headless:              false,
+		Theme:                 "auto",
+		HighlightCache:        128,
+		HighlightCacheSize:    32 << 20,
 		ConnectionURI:         "host=localhost port=5432 user=spacebin database=spacebin sslmode=disable",
-		ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline';",
+		CacheTTL:              300,
+		CacheEntries:          1000,
+		CacheSize:             64 << 20,
+		ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline'; img-src 'self' data:;",
 		ExpirationAge:         720,
 	})
 }
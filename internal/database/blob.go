@@ -0,0 +1,425 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// ErrBlobNotFound is returned by a BlobStore when nothing is stored under a key.
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobStore keeps document content outside of the database. Content is stored
+// under the hex-encoded SHA-256 hash of itself, so identical content is only
+// stored once.
+type BlobStore interface {
+	// Put stores size bytes read from r under key. Content that's already
+	// stored may be left as it is.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+
+	// Get opens the content stored under key, returning ErrBlobNotFound if
+	// there's nothing there. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+
+	// Delete removes the content stored under key. Deleting content that isn't
+	// there isn't an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// ContentStreamer is implemented by databases that can save content as it's
+// read, rather than needing all of it in memory first.
+type ContentStreamer interface {
+	// CreateDocumentFrom creates a document with the content read from r, which
+	// is also the content of its file if it has one.
+	CreateDocumentFrom(ctx context.Context, document Document, r io.Reader) error
+}
+
+// Streamer returns the ContentStreamer of db, or of the database it caches, and
+// whether there is one.
+func Streamer(db Database) (ContentStreamer, bool) {
+	if cached, ok := db.(*CachedDatabase); ok {
+		db = cached.Database
+	}
+
+	streamer, ok := db.(ContentStreamer)
+	return streamer, ok
+}
+
+// isContentHash reports whether key looks like a key returned by HashContent.
+// Stores check keys with it, so they can't be used to reach anything else.
+func isContentHash(key string) bool {
+	if len(key) != sha256.Size*2 {
+		return false
+	}
+
+	_, err := hex.DecodeString(key)
+	return err == nil
+}
+
+// BlobDatabase wraps a Database, keeping the content of new documents and
+// revisions in a BlobStore. Only the content's hash is kept in the database.
+// Documents saved before the store was set up are still read from the database.
+//
+// Content is shared between every document with the same hash, so it's only
+// deleted from the store once the database has released it.
+type BlobDatabase struct {
+	Database
+	store BlobStore
+
+	// Held for reading while content is stored and saved to the database, and
+	// for writing while released content is deleted, so content can't be
+	// deleted between being stored and the database using it again.
+	mu sync.RWMutex
+}
+
+func NewBlobDatabase(db Database, store BlobStore) Database {
+	return &BlobDatabase{Database: db, store: store}
+}
+
+// write runs a change to the database, then deletes any content it released
+// from the store once the change has been committed.
+func (b *BlobDatabase) write(ctx context.Context, change func(ctx context.Context) error) error {
+	ctx, released := withReleasedContent(ctx)
+
+	b.mu.RLock()
+	err := change(ctx)
+	b.mu.RUnlock()
+
+	if err != nil {
+		return err
+	}
+
+	b.deleteReleased(ctx, released.hashes)
+	return nil
+}
+
+// deleteReleased deletes content the database has released from the store,
+// unless it's been saved again since. Failures are only logged, as the
+// documents that used the content are already gone.
+func (b *BlobDatabase) deleteReleased(ctx context.Context, hashes []string) {
+	if len(hashes) == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, hash := range hashes {
+		_, err := b.Database.GetContent(ctx, hash)
+
+		if err == nil {
+			continue
+		}
+
+		if errors.Is(err, sql.ErrNoRows) {
+			err = b.store.Delete(ctx, hash)
+		}
+
+		if err != nil {
+			log.Error().
+				Err(err).
+				Str("hash", hash).
+				Msg("Could not delete released content")
+		}
+	}
+}
+
+// put saves content to the store, returning its hash.
+func (b *BlobDatabase) put(ctx context.Context, content string) (string, error) {
+	hash := HashContent(content)
+
+	if err := b.store.Put(ctx, hash, strings.NewReader(content), int64(len(content))); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// emptyHash is the hash of empty content, which never needs to be read from the store.
+var emptyHash = HashContent("")
+
+// get reads the content stored under hash. If the database already had the
+// content, it was saved before the store was set up, and is used as it is.
+func (b *BlobDatabase) get(ctx context.Context, hash, content string) (string, error) {
+	if content != "" || hash == emptyHash {
+		return content, nil
+	}
+
+	r, err := b.store.Get(ctx, hash)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer r.Close()
+
+	w := new(strings.Builder)
+
+	if _, err := io.Copy(w, r); err != nil {
+		return "", err
+	}
+
+	return w.String(), nil
+}
+
+// storeDocument moves a document's content, and that of its files, into the
+// store, leaving their hashes in its place.
+func (b *BlobDatabase) storeDocument(ctx context.Context, document Document) (Document, error) {
+	hash, err := b.put(ctx, document.Content)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	// The database still records how large the content is
+	document.Content, document.ContentHash, document.Size = "", hash, int64(len(document.Content))
+
+	// Copy the files, so the caller's aren't changed
+	files := make([]File, len(document.Files))
+
+	for i, file := range document.Files {
+		if file.ContentHash, err = b.put(ctx, file.Content); err != nil {
+			return Document{}, err
+		}
+
+		file.Content, file.Size = "", int64(len(file.Content))
+		files[i] = file
+	}
+
+	if document.Files != nil {
+		document.Files = files
+	}
+
+	return document, nil
+}
+
+// loadDocument fills in the content of a document and its files from the store.
+func (b *BlobDatabase) loadDocument(ctx context.Context, document Document, err error) (Document, error) {
+	if err != nil {
+		return document, err
+	}
+
+	document.Content, err = b.get(ctx, document.ContentHash, document.Content)
+
+	for i := 0; i < len(document.Files) && err == nil; i++ {
+		document.Files[i].Content, err = b.get(ctx, document.Files[i].ContentHash, document.Files[i].Content)
+	}
+
+	return document, err
+}
+
+// loadRevision fills in a revision's content from the store.
+func (b *BlobDatabase) loadRevision(ctx context.Context, revision Revision, err error) (Revision, error) {
+	if err != nil {
+		return revision, err
+	}
+
+	revision.Content, err = b.get(ctx, revision.ContentHash, revision.Content)
+	return revision, err
+}
+
+func (b *BlobDatabase) GetDocument(ctx context.Context, id string) (Document, error) {
+	document, err := b.Database.GetDocument(ctx, id)
+	return b.loadDocument(ctx, document, err)
+}
+
+func (b *BlobDatabase) GetAndDeleteDocument(ctx context.Context, id string) (Document, error) {
+	var document Document
+
+	// The content is read before it's deleted from the store
+	err := b.write(ctx, func(ctx context.Context) error {
+		deleted, err := b.Database.GetAndDeleteDocument(ctx, id)
+		document, err = b.loadDocument(ctx, deleted, err)
+		return err
+	})
+
+	return document, err
+}
+
+func (b *BlobDatabase) CreateDocument(ctx context.Context, document Document) error {
+	return b.write(ctx, func(ctx context.Context) error {
+		document, err := b.storeDocument(ctx, document)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Database.CreateDocument(ctx, document)
+	})
+}
+
+// CreateDocumentFrom spools content to a temporary file, hashing it as it's
+// read, so that it can be stored under its hash without being held in memory.
+func (b *BlobDatabase) CreateDocumentFrom(ctx context.Context, document Document, r io.Reader) error {
+	f, err := os.CreateTemp("", "spacebin-upload-*")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(f, h), r)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	hash := hex.EncodeToString(h.Sum(nil))
+
+	return b.write(ctx, func(ctx context.Context) error {
+		if err := b.store.Put(ctx, hash, f, size); err != nil {
+			return err
+		}
+
+		document.Content, document.ContentHash, document.Size = "", hash, size
+
+		// Copy the files, so the caller's aren't changed
+		files := make([]File, len(document.Files))
+
+		for i, file := range document.Files {
+			file.Content, file.ContentHash, file.Size = "", hash, size
+			files[i] = file
+		}
+
+		if document.Files != nil {
+			document.Files = files
+		}
+
+		return b.Database.CreateDocument(ctx, document)
+	})
+}
+
+func (b *BlobDatabase) UpsertDocument(ctx context.Context, document Document) error {
+	return b.write(ctx, func(ctx context.Context) error {
+		document, err := b.storeDocument(ctx, document)
+
+		if err != nil {
+			return err
+		}
+
+		return b.Database.UpsertDocument(ctx, document)
+	})
+}
+
+func (b *BlobDatabase) UpdateDocument(ctx context.Context, document Document) (Revision, error) {
+	var revision Revision
+
+	err := b.write(ctx, func(ctx context.Context) error {
+		content := document.Content
+		document, err := b.storeDocument(ctx, document)
+
+		if err != nil {
+			return err
+		}
+
+		if revision, err = b.Database.UpdateDocument(ctx, document); err != nil {
+			return err
+		}
+
+		// The content was just written, so there's no need to read it back
+		revision.Content = content
+		return nil
+	})
+
+	return revision, err
+}
+
+func (b *BlobDatabase) DeleteDocument(ctx context.Context, id string) error {
+	return b.write(ctx, func(ctx context.Context) error {
+		return b.Database.DeleteDocument(ctx, id)
+	})
+}
+
+func (b *BlobDatabase) DeleteExpiredDocuments(ctx context.Context, now time.Time) (int64, error) {
+	return b.DeleteDocuments(ctx, DocumentFilter{ExpiredBy: now})
+}
+
+func (b *BlobDatabase) DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error) {
+	var n int64
+
+	err := b.write(ctx, func(ctx context.Context) error {
+		var err error
+		n, err = b.Database.DeleteDocuments(ctx, filter)
+		return err
+	})
+
+	return n, err
+}
+
+func (b *BlobDatabase) GetRevisions(ctx context.Context, id string) ([]Revision, error) {
+	revisions, err := b.Database.GetRevisions(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range revisions {
+		if revisions[i], err = b.loadRevision(ctx, revisions[i], nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return revisions, nil
+}
+
+func (b *BlobDatabase) GetRevision(ctx context.Context, id string, revision int) (Revision, error) {
+	rev, err := b.Database.GetRevision(ctx, id, revision)
+	return b.loadRevision(ctx, rev, err)
+}
+
+func (b *BlobDatabase) ImportDocument(ctx context.Context, document Document, revisions []Revision,
+	overwrite bool) error {
+	return b.write(ctx, func(ctx context.Context) error {
+		document, err := b.storeDocument(ctx, document)
+
+		if err != nil {
+			return err
+		}
+
+		// Copy the revisions, so the caller's aren't changed
+		stored := make([]Revision, len(revisions))
+
+		for i, rev := range revisions {
+			if rev.ContentHash, err = b.put(ctx, rev.Content); err != nil {
+				return err
+			}
+
+			rev.Content = ""
+			stored[i] = rev
+		}
+
+		return b.Database.ImportDocument(ctx, document, stored, overwrite)
+	})
+}
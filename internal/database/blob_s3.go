@@ -0,0 +1,152 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3Store is a BlobStore that keeps content in an S3 bucket, or any storage
+// service with an S3-compatible API, like MinIO.
+type S3Store struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3Store connects to the bucket described by a URI of the form
+// s3://[access_key:secret_key@]bucket[/prefix][?endpoint=host:port&region=region&secure=false].
+// If the URI has no credentials, they're read from the environment variables
+// used by the AWS and MinIO command line tools.
+func NewS3Store(uri *url.URL) (*S3Store, error) {
+	query := uri.Query()
+	endpoint := query.Get("endpoint")
+
+	if endpoint == "" {
+		endpoint = "s3.amazonaws.com"
+	}
+
+	region := query.Get("region")
+
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	secure := true
+
+	if s := query.Get("secure"); s != "" {
+		var err error
+
+		if secure, err = strconv.ParseBool(s); err != nil {
+			return nil, fmt.Errorf("secure: %w", err)
+		}
+	}
+
+	creds := credentials.NewChainCredentials([]credentials.Provider{
+		&credentials.EnvAWS{},
+		&credentials.EnvMinio{},
+	})
+
+	if password, ok := uri.User.Password(); ok {
+		creds = credentials.NewStaticV4(uri.User.Username(), password, "")
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  creds,
+		Secure: secure,
+		Region: region, // Setting the region saves looking it up before every request
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &S3Store{
+		client: client,
+		bucket: uri.Host,
+		prefix: strings.Trim(uri.Path, "/"),
+	}, nil
+}
+
+func (s *S3Store) object(key string) (string, error) {
+	if !isContentHash(key) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+
+	return path.Join(s.prefix, key), nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	object, err := s.object(key)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = s.client.PutObject(ctx, s.bucket, object, r, size, minio.PutObjectOptions{
+		ContentType: "text/plain; charset=utf-8",
+	})
+
+	return err
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	object, err := s.object(key)
+
+	if err != nil {
+		return nil, err
+	}
+
+	obj, err := s.client.GetObject(ctx, s.bucket, object, minio.GetObjectOptions{})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Objects are fetched lazily, so check the object exists before handing it out
+	if _, err := obj.Stat(); err != nil {
+		obj.Close()
+
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, ErrBlobNotFound
+		}
+
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	object, err := s.object(key)
+
+	if err != nil {
+		return err
+	}
+
+	// S3 doesn't treat removing an object that isn't there as an error
+	return s.client.RemoveObject(ctx, s.bucket, object, minio.RemoveObjectOptions{})
+}
@@ -0,0 +1,332 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func readBlob(t *testing.T, store BlobStore, key string) string {
+	r, err := store.Get(context.Background(), key)
+	require.NoError(t, err)
+
+	defer r.Close()
+
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+
+	return string(content)
+}
+
+// testBlobStore runs the tests every BlobStore should pass.
+func testBlobStore(t *testing.T, store BlobStore) {
+	ctx := context.Background()
+	key := HashContent("hello")
+
+	_, err := store.Get(ctx, key)
+	require.ErrorIs(t, err, ErrBlobNotFound)
+
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("hello"), 5))
+	require.Equal(t, "hello", readBlob(t, store, key))
+
+	// Storing the same content again is fine
+	require.NoError(t, store.Put(ctx, key, strings.NewReader("hello"), 5))
+	require.Equal(t, "hello", readBlob(t, store, key))
+
+	// Keys that aren't hashes are refused
+	require.Error(t, store.Put(ctx, "../escape", strings.NewReader("hello"), 5))
+
+	_, err = store.Get(ctx, "../escape")
+	require.Error(t, err)
+
+	// Deleted content is gone, and deleting it again is fine
+	require.NoError(t, store.Delete(ctx, key))
+
+	_, err = store.Get(ctx, key)
+	require.ErrorIs(t, err, ErrBlobNotFound)
+
+	require.NoError(t, store.Delete(ctx, key))
+	require.Error(t, store.Delete(ctx, "../escape"))
+}
+
+func TestFileStore(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+// TestS3Store runs against a fake S3 server, or a real one like MinIO if
+// SPIRIT_TEST_S3_URI is set to the URI of an empty bucket.
+func TestS3Store(t *testing.T) {
+	uri := os.Getenv("SPIRIT_TEST_S3_URI")
+
+	if uri == "" {
+		srv := httptest.NewServer(newFakeS3())
+		t.Cleanup(srv.Close)
+
+		uri = "s3://access:secret@bucket/prefix?secure=false&endpoint=" + strings.TrimPrefix(srv.URL, "http://")
+	}
+
+	u, err := url.Parse(uri)
+	require.NoError(t, err)
+
+	store, err := NewS3Store(u)
+	require.NoError(t, err)
+
+	testBlobStore(t, store)
+}
+
+func TestBlobDatabase(t *testing.T) {
+	ctx := context.Background()
+	sqlite := newTestSQLite(t)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	db := NewBlobDatabase(sqlite, store)
+
+	// Content goes to the store, and only its hash to the database
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: "hello"}))
+
+	var content, hash string
+	require.NoError(t, sqlite.QueryRow("SELECT content, content_hash FROM documents WHERE id='12345678'").
+		Scan(&content, &hash))
+	require.Empty(t, content)
+	require.Equal(t, HashContent("hello"), hash)
+	require.Equal(t, "hello", readBlob(t, store, hash))
+
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "hello", document.Content)
+
+	// Edits and their revisions go through the store too
+	revision, err := db.UpdateDocument(ctx, Document{ID: "12345678", Content: "hello, world"})
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", revision.Content)
+
+	revisions, err := db.GetRevisions(ctx, "12345678")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, "hello", revisions[0].Content)
+	require.Equal(t, "hello, world", revisions[1].Content)
+
+	first, err := db.GetRevision(ctx, "12345678", 1)
+	require.NoError(t, err)
+	require.Equal(t, "hello", first.Content)
+
+	// So do files
+	files := []File{{Name: "a.txt", Content: "first file"}, {Name: "b.txt", Content: "second file"}}
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "files000", Content: "first file", Files: files}))
+	require.Equal(t, "first file", files[0].Content)
+	require.Equal(t, "second file", readBlob(t, store, HashContent("second file")))
+
+	document, err = db.GetDocument(ctx, "files000")
+	require.NoError(t, err)
+	require.Equal(t, "second file", document.Files[1].Content)
+	require.Equal(t, int64(len("second file")), document.Files[1].Size)
+	require.Equal(t, int64(len("first file")+len("second file")), document.Size)
+
+	// Only the store has their content, but the database knows how large it is
+	stored, err := sqlite.GetContent(ctx, HashContent("second file"))
+	require.NoError(t, err)
+	require.Empty(t, stored.Content)
+
+	// Imported documents and their revisions go through the store too
+	require.NoError(t, db.ImportDocument(ctx, Document{ID: "imported", Content: "imported"},
+		[]Revision{{Revision: 1, Content: "original"}, {Revision: 2, Content: "imported"}}, false))
+	require.Equal(t, "original", readBlob(t, store, HashContent("original")))
+
+	revisions, err = db.GetRevisions(ctx, "imported")
+	require.NoError(t, err)
+	require.Equal(t, "original", revisions[0].Content)
+
+	// Content is deleted from the store once nothing uses it
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "shared00", Content: "second file"}))
+	require.NoError(t, db.DeleteDocument(ctx, "files000"))
+	require.Equal(t, "second file", readBlob(t, store, HashContent("second file")))
+
+	require.NoError(t, db.DeleteDocument(ctx, "shared00"))
+
+	_, err = store.Get(ctx, HashContent("second file"))
+	require.ErrorIs(t, err, ErrBlobNotFound)
+
+	// Burned and expired documents too
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "burn0000", Content: "secret", BurnAfterRead: true}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "expired0", Content: "old", ExpiresAt: &expired}))
+
+	document, err = db.GetAndDeleteDocument(ctx, "burn0000")
+	require.NoError(t, err)
+	require.Equal(t, "secret", document.Content)
+
+	n, err := db.DeleteExpiredDocuments(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	for _, content := range []string{"secret", "old"} {
+		_, err = store.Get(ctx, HashContent(content))
+		require.ErrorIs(t, err, ErrBlobNotFound, content)
+	}
+
+	// Documents saved before the store was set up are read from the database
+	require.NoError(t, sqlite.CreateDocument(ctx, Document{ID: "legacy00", Content: "legacy"}))
+
+	document, err = db.GetAndDeleteDocument(ctx, "legacy00")
+	require.NoError(t, err)
+	require.Equal(t, "legacy", document.Content)
+}
+
+func TestBlobDatabaseStream(t *testing.T) {
+	ctx := context.Background()
+	sqlite := newTestSQLite(t)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	store, err := NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	// Databases without a store can't stream content, but those with one still
+	// can once they're cached
+	_, ok := Streamer(sqlite)
+	require.False(t, ok)
+
+	streamer, ok := Streamer(NewCachedDatabase(NewBlobDatabase(sqlite, store), NewMemoryCache(10, 0), time.Hour))
+	require.True(t, ok)
+
+	content := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 1024)
+	require.NoError(t, streamer.CreateDocumentFrom(ctx, Document{
+		ID:          "12345678",
+		ContentType: "image/png",
+		Files:       []File{{Name: "image.png", ContentType: "image/png"}},
+	}, strings.NewReader(content)))
+
+	require.Equal(t, content, readBlob(t, store, HashContent(content)))
+
+	document, err := NewBlobDatabase(sqlite, store).GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, content, document.Content)
+	require.Equal(t, HashContent(content), document.ContentHash)
+	require.EqualValues(t, len(content), document.Size)
+	require.Len(t, document.Files, 1)
+	require.Equal(t, content, document.Files[0].Content)
+}
+
+// fakeS3 is the bare minimum of the S3 API needed by S3Store.
+type fakeS3 struct {
+	sync.Mutex
+	objects map[string][]byte
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: map[string][]byte{}}
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.Lock()
+	defer f.Unlock()
+
+	switch r.Method {
+	case http.MethodPut:
+		body, err := readS3Body(r)
+
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		f.objects[r.URL.Path] = body
+		w.Header().Set("ETag", `"fake"`)
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet, http.MethodHead:
+		body, ok := f.objects[r.URL.Path]
+
+		if !ok {
+			w.Header().Set("Content-Type", "application/xml")
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, "<Error><Code>NoSuchKey</Code><Key>%s</Key></Error>", r.URL.Path)
+			return
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		w.Header().Set("ETag", `"fake"`)
+		w.Header().Set("Last-Modified", "Mon, 02 Jan 2006 15:04:05 GMT")
+		w.WriteHeader(http.StatusOK)
+
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// readS3Body reads an upload's body, decoding the chunked encoding clients use
+// to sign uploads sent over plain HTTP.
+func readS3Body(r *http.Request) ([]byte, error) {
+	if !strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		return io.ReadAll(r.Body)
+	}
+
+	var body bytes.Buffer
+	br := bufio.NewReader(r.Body)
+
+	for {
+		header, err := br.ReadString('\n')
+
+		if err != nil {
+			return nil, err
+		}
+
+		size, _, _ := strings.Cut(strings.TrimSpace(header), ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if n == 0 {
+			return body.Bytes(), nil
+		}
+
+		if _, err := io.CopyN(&body, br, n); err != nil {
+			return nil, err
+		}
+
+		if _, err := br.Discard(2); err != nil { // \r\n after each chunk
+			return nil, err
+		}
+	}
+}
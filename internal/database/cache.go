@@ -0,0 +1,218 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync/atomic"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Cache keeps copies of documents, so they don't have to be read from the
+// database every time they're viewed.
+type Cache interface {
+	// Get returns the document cached under id, and whether there was one.
+	Get(ctx context.Context, id string) (Document, bool, error)
+
+	// Set caches a document for ttl.
+	Set(ctx context.Context, document Document, ttl time.Duration) error
+
+	// Delete removes the document cached under id, if there is one.
+	Delete(ctx context.Context, id string) error
+}
+
+// CachedDatabase wraps a Database, keeping documents read with GetDocument in a
+// Cache. Documents are removed from the cache when they're edited or deleted,
+// and are never cached past their expiry time.
+//
+// Documents that are burned after reading are never cached, so their content
+// only ever exists in the database.
+type CachedDatabase struct {
+	Database
+	cache Cache
+	ttl   time.Duration
+
+	// generation counts invalidations, so that a document read from the database
+	// before one isn't left cached after it
+	generation atomic.Uint64
+}
+
+// NewCachedDatabase wraps db, caching documents for at most ttl.
+func NewCachedDatabase(db Database, cache Cache, ttl time.Duration) Database {
+	return &CachedDatabase{Database: db, cache: cache, ttl: ttl}
+}
+
+// invalidate removes the cached copy of a document that has been changed or
+// deleted. The change has already been made by the time it's called, so failures
+// are only logged, and the copy lasts until its TTL runs out.
+func (c *CachedDatabase) invalidate(ctx context.Context, id string) {
+	c.generation.Add(1)
+
+	if err := c.cache.Delete(ctx, id); err != nil {
+		log.Warn().
+			Err(err).
+			Str("id", id).
+			Msg("Could not remove document from cache")
+	}
+}
+
+// cacheTTL returns how long a document may be cached for. It's zero if it
+// shouldn't be cached at all.
+func (c *CachedDatabase) cacheTTL(document Document) time.Duration {
+	if document.BurnAfterRead {
+		return 0
+	}
+
+	ttl := c.ttl
+
+	if document.ExpiresAt != nil {
+		ttl = min(ttl, time.Until(*document.ExpiresAt))
+	}
+
+	return max(ttl, 0)
+}
+
+func (c *CachedDatabase) GetDocument(ctx context.Context, id string) (Document, error) {
+	document, ok, err := c.cache.Get(ctx, id)
+
+	// A broken cache only makes reads slower, so fall back to the database
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("id", id).
+			Msg("Could not read document from cache")
+	}
+
+	if ok {
+		return document, nil
+	}
+
+	generation := c.generation.Load()
+	document, err = c.Database.GetDocument(ctx, id)
+
+	if err != nil {
+		return document, err
+	}
+
+	if ttl := c.cacheTTL(document); ttl > 0 {
+		if err := c.cache.Set(ctx, document, ttl); err != nil {
+			log.Warn().
+				Err(err).
+				Str("id", id).
+				Msg("Could not cache document")
+		}
+
+		// If a document was changed or deleted while this one was being read, it
+		// may have been this one, and the copy just cached may be out of date
+		if c.generation.Load() != generation {
+			c.invalidate(ctx, id)
+		}
+	}
+
+	return document, nil
+}
+
+func (c *CachedDatabase) UpsertDocument(ctx context.Context, document Document) error {
+	if err := c.Database.UpsertDocument(ctx, document); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, document.ID)
+	return nil
+}
+
+func (c *CachedDatabase) UpdateDocument(ctx context.Context, document Document) (Revision, error) {
+	revision, err := c.Database.UpdateDocument(ctx, document)
+
+	if err != nil {
+		return revision, err
+	}
+
+	c.invalidate(ctx, document.ID)
+	return revision, nil
+}
+
+func (c *CachedDatabase) DeleteDocument(ctx context.Context, id string) error {
+	if err := c.Database.DeleteDocument(ctx, id); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, id)
+	return nil
+}
+
+func (c *CachedDatabase) GetAndDeleteDocument(ctx context.Context, id string) (Document, error) {
+	document, err := c.Database.GetAndDeleteDocument(ctx, id)
+
+	if err != nil {
+		return document, err
+	}
+
+	// Documents burned after reading are never cached, so there's nothing to remove
+	if !document.BurnAfterRead {
+		c.invalidate(ctx, id)
+	}
+
+	return document, nil
+}
+
+// DeleteDocuments removes the documents matching a filter, and their cached copies.
+func (c *CachedDatabase) DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error) {
+	filter.Limit = 0
+	documents, err := c.Database.ListDocuments(ctx, filter)
+
+	if err != nil {
+		return 0, err
+	}
+
+	deleted, err := c.Database.DeleteDocuments(ctx, filter)
+
+	if err != nil {
+		return deleted, err
+	}
+
+	for _, document := range documents {
+		c.invalidate(ctx, document.ID)
+	}
+
+	return deleted, nil
+}
+
+func (c *CachedDatabase) ImportDocument(ctx context.Context, document Document, revisions []Revision,
+	overwrite bool) error {
+	if err := c.Database.ImportDocument(ctx, document, revisions, overwrite); err != nil {
+		return err
+	}
+
+	c.invalidate(ctx, document.ID)
+	return nil
+}
+
+// Close closes the database, and the cache if it needs closing.
+func (c *CachedDatabase) Close() error {
+	err := c.Database.Close()
+
+	if closer, ok := c.cache.(io.Closer); ok {
+		err = errors.Join(err, closer.Close())
+	}
+
+	return err
+}
@@ -0,0 +1,137 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryCache is a Cache that keeps documents in memory, evicting the least
+// recently used ones when it's full.
+type MemoryCache struct {
+	sync.Mutex
+
+	maxEntries int
+	maxBytes   int64
+	bytes      int64
+
+	entries map[string]*list.Element
+	order   *list.List // Most recently used first
+}
+
+type memoryCacheEntry struct {
+	document  Document
+	expiresAt time.Time
+	size      int64
+}
+
+// NewMemoryCache creates a MemoryCache holding at most maxEntries documents,
+// with at most maxBytes of content between them. Either limit is ignored if
+// it's zero.
+func NewMemoryCache(maxEntries int, maxBytes int64) *MemoryCache {
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+func (m *MemoryCache) Get(ctx context.Context, id string) (Document, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+
+	el, ok := m.entries[id]
+
+	if !ok {
+		return Document{}, false, nil
+	}
+
+	entry := el.Value.(*memoryCacheEntry)
+
+	if !time.Now().Before(entry.expiresAt) {
+		m.remove(el)
+		return Document{}, false, nil
+	}
+
+	m.order.MoveToFront(el)
+	return entry.document, true, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, document Document, ttl time.Duration) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if el, ok := m.entries[document.ID]; ok {
+		m.remove(el)
+	}
+
+	entry := &memoryCacheEntry{
+		document:  document,
+		expiresAt: time.Now().Add(ttl),
+		size:      int64(len(document.ID) + len(document.Content)),
+	}
+
+	for _, file := range document.Files {
+		entry.size += int64(len(file.Name) + len(file.Content))
+	}
+
+	// Documents that could never fit aren't worth evicting everything else for
+	if m.maxBytes > 0 && entry.size > m.maxBytes {
+		return nil
+	}
+
+	m.entries[document.ID] = m.order.PushFront(entry)
+	m.bytes += entry.size
+
+	for (m.maxEntries > 0 && m.order.Len() > m.maxEntries) || (m.maxBytes > 0 && m.bytes > m.maxBytes) {
+		m.remove(m.order.Back())
+	}
+
+	return nil
+}
+
+func (m *MemoryCache) Delete(ctx context.Context, id string) error {
+	m.Lock()
+	defer m.Unlock()
+
+	if el, ok := m.entries[id]; ok {
+		m.remove(el)
+	}
+
+	return nil
+}
+
+// Len returns the number of cached documents, including expired ones that
+// haven't been evicted yet.
+func (m *MemoryCache) Len() int {
+	m.Lock()
+	defer m.Unlock()
+
+	return m.order.Len()
+}
+
+// remove evicts a cache entry. The caller must hold the lock.
+func (m *MemoryCache) remove(el *list.Element) {
+	entry := m.order.Remove(el).(*memoryCacheEntry)
+
+	delete(m.entries, entry.document.ID)
+	m.bytes -= entry.size
+}
@@ -0,0 +1,85 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisCache is a Cache that keeps documents in Redis, or any server speaking
+// its protocol, so that they can be shared between instances.
+type RedisCache struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisCache connects to the Redis server described by a URI of the form
+// redis://[[user]:password@]host[:port][/db]. Use rediss:// for TLS.
+func NewRedisCache(uri string) (*RedisCache, error) {
+	options, err := redis.ParseURL(uri)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &RedisCache{redis.NewClient(options), "spacebin:document:"}, nil
+}
+
+func (r *RedisCache) Get(ctx context.Context, id string) (Document, bool, error) {
+	data, err := r.client.Get(ctx, r.prefix+id).Bytes()
+
+	if errors.Is(err, redis.Nil) {
+		return Document{}, false, nil
+	}
+
+	if err != nil {
+		return Document{}, false, err
+	}
+
+	var document Document
+
+	// Documents are encoded with gob rather than JSON, which leaves out their token hashes
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&document); err != nil {
+		return Document{}, false, err
+	}
+
+	return document, true, nil
+}
+
+func (r *RedisCache) Set(ctx context.Context, document Document, ttl time.Duration) error {
+	var data bytes.Buffer
+
+	if err := gob.NewEncoder(&data).Encode(document); err != nil {
+		return err
+	}
+
+	return r.client.Set(ctx, r.prefix+document.ID, data.Bytes(), ttl).Err()
+}
+
+func (r *RedisCache) Delete(ctx context.Context, id string) error {
+	return r.client.Del(ctx, r.prefix+id).Err()
+}
+
+func (r *RedisCache) Close() error {
+	return r.client.Close()
+}
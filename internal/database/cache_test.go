@@ -0,0 +1,243 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/stretchr/testify/require"
+)
+
+// testCache checks the behaviour every Cache should have.
+func testCache(t *testing.T, cache Cache) {
+	ctx := context.Background()
+
+	_, ok, err := cache.Get(ctx, "12345678")
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	expiresAt := time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)
+	document := Document{
+		ID:              "12345678",
+		Content:         "hello",
+		CreatedAt:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:       time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ExpiresAt:       &expiresAt,
+		DeleteTokenHash: "delete",
+		EditTokenHash:   "edit",
+		ContentHash:     HashContent("hello"),
+	}
+
+	require.NoError(t, cache.Set(ctx, document, time.Minute))
+
+	// Everything is kept, including what isn't sent to clients
+	cached, ok, err := cache.Get(ctx, "12345678")
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, document, cached)
+
+	require.NoError(t, cache.Delete(ctx, "12345678"))
+	require.NoError(t, cache.Delete(ctx, "12345678")) // Deleting again does nothing
+
+	_, ok, err = cache.Get(ctx, "12345678")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestMemoryCache(t *testing.T) {
+	testCache(t, NewMemoryCache(0, 0))
+}
+
+func TestMemoryCacheEviction(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(2, 20)
+
+	require.NoError(t, cache.Set(ctx, Document{ID: "a", Content: "1"}, time.Minute))
+	require.NoError(t, cache.Set(ctx, Document{ID: "b", Content: "2"}, time.Minute))
+
+	// Reading a keeps it, so b is the least recently used
+	_, ok, _ := cache.Get(ctx, "a")
+	require.True(t, ok)
+
+	require.NoError(t, cache.Set(ctx, Document{ID: "c", Content: "3"}, time.Minute))
+	require.Equal(t, 2, cache.Len())
+
+	_, ok, _ = cache.Get(ctx, "b")
+	require.False(t, ok)
+
+	// Large documents push out as many others as they need to
+	require.NoError(t, cache.Set(ctx, Document{ID: "d", Content: "0123456789abcdefgh"}, time.Minute))
+	require.Equal(t, 1, cache.Len())
+
+	// Documents that could never fit aren't cached
+	require.NoError(t, cache.Set(ctx, Document{ID: "e", Content: "0123456789abcdef0123456789"}, time.Minute))
+
+	_, ok, _ = cache.Get(ctx, "e")
+	require.False(t, ok)
+
+	_, ok, _ = cache.Get(ctx, "d")
+	require.True(t, ok)
+}
+
+func TestMemoryCacheExpiry(t *testing.T) {
+	ctx := context.Background()
+	cache := NewMemoryCache(0, 0)
+
+	require.NoError(t, cache.Set(ctx, Document{ID: "a"}, time.Millisecond))
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, ok)
+	require.Equal(t, 0, cache.Len())
+}
+
+func newTestRedisCache(t *testing.T) (*RedisCache, *miniredis.Miniredis) {
+	server := miniredis.RunT(t)
+	cache, err := NewRedisCache("redis://" + server.Addr())
+	require.NoError(t, err)
+
+	t.Cleanup(func() { cache.Close() })
+
+	return cache, server
+}
+
+func TestRedisCache(t *testing.T) {
+	cache, server := newTestRedisCache(t)
+	testCache(t, cache)
+
+	// Redis takes care of expiry
+	ctx := context.Background()
+	require.NoError(t, cache.Set(ctx, Document{ID: "a"}, time.Minute))
+	require.Equal(t, time.Minute, server.TTL("spacebin:document:a"))
+
+	server.FastForward(time.Minute)
+
+	_, ok, err := cache.Get(ctx, "a")
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestCachedDatabase(t *testing.T) {
+	ctx := context.Background()
+	sqlite := newTestSQLite(t)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	cache, server := newTestRedisCache(t)
+	db := NewCachedDatabase(sqlite, cache, time.Hour)
+
+	expiresAt := time.Now().Add(10 * time.Minute)
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: "hello", ExpiresAt: &expiresAt}))
+
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "hello", document.Content)
+
+	// The document is cached until it expires, rather than for the full hour
+	ttl := server.TTL("spacebin:document:12345678")
+	require.InDelta(t, 10*time.Minute, ttl, float64(5*time.Second))
+
+	// Edits replace the cached copy
+	_, err = db.UpdateDocument(ctx, Document{ID: "12345678", Content: "hello, world"})
+	require.NoError(t, err)
+	require.False(t, server.Exists("spacebin:document:12345678"))
+
+	document, err = db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", document.Content)
+
+	// Deleted documents are removed from the cache
+	require.NoError(t, db.DeleteDocument(ctx, "12345678"))
+	require.False(t, server.Exists("spacebin:document:12345678"))
+
+	// Documents burned after reading are never cached
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "burnburn", Content: "secret", BurnAfterRead: true}))
+
+	_, err = db.GetDocument(ctx, "burnburn")
+	require.NoError(t, err)
+	require.False(t, server.Exists("spacebin:document:burnburn"))
+
+	// Documents deleted in bulk are removed from the cache
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "bulk0001", Content: "bulk"}))
+
+	_, err = db.GetDocument(ctx, "bulk0001")
+	require.NoError(t, err)
+	require.True(t, server.Exists("spacebin:document:bulk0001"))
+
+	deleted, err := db.DeleteDocuments(ctx, DocumentFilter{Prefix: "bulk"})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, deleted)
+	require.False(t, server.Exists("spacebin:document:bulk0001"))
+
+	// If the cache is down, documents are still read from the database, and
+	// changes made to them aren't reported as failed
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "87654321", Content: "still here"}))
+	server.Close()
+
+	document, err = db.GetDocument(ctx, "87654321")
+	require.NoError(t, err)
+	require.Equal(t, "still here", document.Content)
+
+	require.NoError(t, db.DeleteDocument(ctx, "87654321"))
+}
+
+// slowDatabase runs read after reading a document, as if another request had
+// got in while it was being read.
+type slowDatabase struct {
+	Database
+	read func()
+}
+
+func (s *slowDatabase) GetDocument(ctx context.Context, id string) (Document, error) {
+	document, err := s.Database.GetDocument(ctx, id)
+	s.read()
+
+	return document, err
+}
+
+// TestCachedDatabaseStaleRead tests that a document edited while it was being
+// read isn't left in the cache as it was before
+func TestCachedDatabaseStaleRead(t *testing.T) {
+	ctx := context.Background()
+	sqlite := newTestSQLite(t)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	cache, server := newTestRedisCache(t)
+	slow := &slowDatabase{Database: sqlite, read: func() {}}
+	db := NewCachedDatabase(slow, cache, time.Hour)
+
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: "hello"}))
+
+	slow.read = func() {
+		slow.read = func() {}
+
+		_, err := db.UpdateDocument(ctx, Document{ID: "12345678", Content: "hello, world"})
+		require.NoError(t, err)
+	}
+
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "hello", document.Content)
+	require.False(t, server.Exists("spacebin:document:12345678"))
+
+	document, err = db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", document.Content)
+}
@@ -0,0 +1,221 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"strings"
+	"unicode/utf8"
+)
+
+// Content is stored once for every document and revision with the same content,
+// keyed by its hash. Documents saved before content was shared keep their own
+// copy, and don't reference any Content.
+type Content struct {
+	Hash    string `db:"hash" json:"hash"`
+	Content string `db:"content" json:"content"` // Empty if the content is kept in a blob store
+
+	// Number of documents and revisions using the content. It's deleted once
+	// nothing uses it.
+	References int `db:"refs" json:"references"`
+}
+
+// contentHash returns the hash a document's content is stored under.
+func contentHash(document Document) string {
+	if document.ContentHash != "" {
+		return document.ContentHash
+	}
+
+	return HashContent(document.Content)
+}
+
+// base64Encoding marks content that's stored base64 encoded.
+const base64Encoding = "base64"
+
+// encodeContent returns content the way it's stored in the contents table, and
+// how it's encoded. Not every database can store text that isn't valid UTF-8
+// or has NUL bytes, so content like that is base64 encoded.
+func encodeContent(content string) (string, string) {
+	if utf8.ValidString(content) && !strings.ContainsRune(content, 0) {
+		return content, ""
+	}
+
+	return base64.StdEncoding.EncodeToString([]byte(content)), base64Encoding
+}
+
+// decodeContent decodes content read from the contents table.
+func decodeContent(content, encoding string) (string, error) {
+	if encoding != base64Encoding {
+		return content, nil
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(content)
+	return string(decoded), err
+}
+
+// holdContent stores a document's content, or adds a reference to it if the
+// same content is already stored, and returns its hash.
+func holdContent(ctx context.Context, tx *sql.Tx, d dialect, document Document) (string, error) {
+	hash := contentHash(document)
+	content, encoding := encodeContent(document.Content)
+
+	// Content kept in a blob store arrives without its content, but with its size
+	size := document.Size
+
+	if document.Content != "" {
+		size = int64(len(document.Content))
+	}
+
+	if _, err := tx.ExecContext(ctx, d.holdContent, content, hash, encoding, size); err != nil {
+		return "", err
+	}
+
+	return hash, nil
+}
+
+// releasedKey is the context key for a *releasedContent.
+type releasedKey struct{}
+
+// releasedContent collects the hashes of content that nothing uses any more,
+// and was deleted from the contents table.
+type releasedContent struct {
+	hashes []string
+}
+
+// withReleasedContent returns a context that collects the hashes of content
+// released while it's used. They're only gone for good once the transaction
+// releasing them commits.
+func withReleasedContent(ctx context.Context) (context.Context, *releasedContent) {
+	released := &releasedContent{}
+	return context.WithValue(ctx, releasedKey{}, released), released
+}
+
+// releaseContent removes a reference to the content stored under hash,
+// deleting it once nothing uses it. Content that's deleted is reported to the
+// context's releasedContent, if it has one.
+func releaseContent(ctx context.Context, tx *sql.Tx, d dialect, hash string) error {
+	// Older documents keep their own content
+	if hash == "" {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE contents SET refs = refs - 1 WHERE hash = "+d.placeholder(1),
+		hash); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM contents WHERE hash = "+d.placeholder(1)+" AND refs <= 0", hash)
+
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if released, ok := ctx.Value(releasedKey{}).(*releasedContent); ok && n > 0 {
+		released.hashes = append(released.hashes, hash)
+	}
+
+	return nil
+}
+
+// deleteDocument deletes a document with its revisions and files, releasing
+// their content. It returns sql.ErrNoRows if the document doesn't exist.
+func deleteDocument(ctx context.Context, tx *sql.Tx, d dialect, id string) error {
+	var hash string
+
+	// Lock the document, so its content can't be changed before it's deleted
+	if err := tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id = "+d.placeholder(1)+d.forUpdate,
+		id).Scan(&hash); err != nil {
+		return err
+	}
+
+	hashes, err := queryStrings(ctx, tx, "SELECT content_hash FROM document_revisions WHERE document_id = "+
+		d.placeholder(1), id)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM documents WHERE id = "+d.placeholder(1), id); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM document_revisions WHERE document_id = "+d.placeholder(1),
+		id); err != nil {
+		return err
+	}
+
+	if err := deleteFiles(ctx, tx, d, id); err != nil {
+		return err
+	}
+
+	for _, hash := range append(hashes, hash) {
+		if err := releaseContent(ctx, tx, d, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// queryStrings runs a query selecting a single string column, returning every value.
+func queryStrings(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var values []string
+
+	for rows.Next() {
+		var value string
+
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// getContent reads the content stored under hash.
+func getContent(ctx context.Context, db *sql.DB, d dialect, hash string) (Content, error) {
+	var content Content
+	var encoding string
+
+	err := db.QueryRowContext(ctx, "SELECT hash, content, encoding, refs FROM contents WHERE hash = "+d.placeholder(1),
+		hash).Scan(&content.Hash, &content.Content, &encoding, &content.References)
+
+	if err != nil {
+		return content, err
+	}
+
+	content.Content, err = decodeContent(content.Content, encoding)
+	return content, err
+}
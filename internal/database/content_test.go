@@ -0,0 +1,181 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireReferences checks how many documents and revisions use content.
+func requireReferences(t *testing.T, db Database, content string, want int) {
+	t.Helper()
+
+	c, err := db.GetContent(context.Background(), HashContent(content))
+
+	if want == 0 {
+		require.ErrorIs(t, err, sql.ErrNoRows, content)
+		return
+	}
+
+	require.NoError(t, err, content)
+	require.Equal(t, content, c.Content)
+	require.Equal(t, want, c.References, content)
+}
+
+func TestContentDeduplication(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	// Each document and its first revision reference the same content
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "aaaaaaaa", Content: "log"}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "bbbbbbbb", Content: "log"}))
+	requireReferences(t, db, "log", 4)
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM contents").Scan(&n))
+	require.Equal(t, 1, n)
+
+	document, err := db.GetDocument(ctx, "bbbbbbbb")
+	require.NoError(t, err)
+	require.Equal(t, "log", document.Content)
+	require.Equal(t, HashContent("log"), document.ContentHash)
+
+	// Editing moves the document's reference, but its first revision keeps one
+	revision, err := db.UpdateDocument(ctx, Document{ID: "aaaaaaaa", Content: "new log"})
+	require.NoError(t, err)
+	require.Equal(t, HashContent("new log"), revision.ContentHash)
+	requireReferences(t, db, "log", 3)
+	requireReferences(t, db, "new log", 2)
+
+	// Content is only freed once nothing uses it
+	require.NoError(t, db.DeleteDocument(ctx, "bbbbbbbb"))
+	requireReferences(t, db, "log", 1)
+
+	document, err = db.GetAndDeleteDocument(ctx, "aaaaaaaa")
+	require.NoError(t, err)
+	require.Equal(t, "new log", document.Content)
+	requireReferences(t, db, "log", 0)
+	requireReferences(t, db, "new log", 0)
+}
+
+func TestContentUpsertDocument(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	require.NoError(t, db.UpsertDocument(ctx, Document{ID: "about", Content: "v1"}))
+	require.NoError(t, db.UpsertDocument(ctx, Document{ID: "about", Content: "v1"}))
+	requireReferences(t, db, "v1", 3)
+
+	require.NoError(t, db.UpsertDocument(ctx, Document{ID: "about", Content: "v2"}))
+	requireReferences(t, db, "v1", 2)
+	requireReferences(t, db, "v2", 2)
+}
+
+// TestContentUpsertUploadedDocument tests that a custom document replacing an
+// uploaded one doesn't keep anything of it but its ID
+func TestContentUpsertUploadedDocument(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	require.NoError(t, db.CreateDocument(ctx, Document{
+		ID:              "about",
+		Content:         "one",
+		DeleteTokenHash: "delete",
+		EditTokenHash:   "edit",
+		Language:        "go",
+		ContentType:     "text/x-go",
+		Files: []File{
+			{Name: "one.go", Content: "one", Language: "go", ContentType: "text/x-go"},
+			{Name: "two.go", Content: "two", Language: "go", ContentType: "text/x-go"},
+		},
+	}))
+
+	require.NoError(t, db.UpsertDocument(ctx, Document{ID: "about", Content: "about"}))
+
+	document, err := db.GetDocument(ctx, "about")
+	require.NoError(t, err)
+	require.Equal(t, "about", document.Content)
+	require.Empty(t, document.DeleteTokenHash)
+	require.Empty(t, document.EditTokenHash)
+	require.Empty(t, document.Language)
+	require.Empty(t, document.ContentType)
+	require.Empty(t, document.Files)
+
+	// Only the first revision still uses the first file's content
+	requireReferences(t, db, "one", 1)
+	requireReferences(t, db, "two", 0)
+}
+
+func TestContentExpiredDocuments(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "aaaaaaaa", Content: "log", ExpiresAt: &expired}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "bbbbbbbb", Content: "log"}))
+
+	n, err := db.DeleteExpiredDocuments(ctx, time.Now())
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	// The document that's still around keeps the content
+	requireReferences(t, db, "log", 2)
+
+	document, err := db.GetDocument(ctx, "bbbbbbbb")
+	require.NoError(t, err)
+	require.Equal(t, "log", document.Content)
+}
+
+func TestBinaryContent(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	png := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR\xff"
+
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: png, ContentType: "image/png",
+		Files: []File{{Name: "image.png", ContentType: "image/png", Content: png}}}))
+
+	// It's stored base64 encoded, but read back as it was
+	var stored, encoding string
+	require.NoError(t, db.QueryRow("SELECT content, encoding FROM contents WHERE hash=$1", HashContent(png)).
+		Scan(&stored, &encoding))
+	require.Equal(t, "base64", encoding)
+	require.NotEqual(t, png, stored)
+
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, png, document.Content)
+	require.Equal(t, "image/png", document.ContentType)
+	require.Equal(t, png, document.Files[0].Content)
+	require.Equal(t, "image/png", document.Files[0].ContentType)
+
+	revision, err := db.GetRevision(ctx, "12345678", 1)
+	require.NoError(t, err)
+	require.Equal(t, png, revision.Content)
+
+	requireReferences(t, db, png, 3)
+}
@@ -18,23 +18,187 @@ package database
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"time"
 
 	_ "github.com/lib/pq"
 )
 
 type Document struct {
-	ID        string    `db:"id" json:"id"`
-	Content   string    `db:"content" json:"content"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
-	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+	ID            string     `db:"id" json:"id"`
+	Content       string     `db:"content" json:"content"`
+	CreatedAt     time.Time  `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time  `db:"updated_at" json:"updated_at"`
+	ExpiresAt     *time.Time `db:"expires_at" json:"expires_at"`           // nil if the document never expires
+	BurnAfterRead bool       `db:"burn_after_read" json:"burn_after_read"` // Delete the document once it has been read
+
+	// Hash of the token needed to delete the document. Empty for documents that
+	// can't be deleted, like custom documents.
+	DeleteTokenHash string `db:"delete_token_hash" json:"-"`
+	// Hash of the token needed to edit the document. Empty for documents that
+	// can't be edited.
+	EditTokenHash string `db:"edit_token_hash" json:"-"`
+	// SHA-256 hash of the content, which is stored once in the contents table no
+	// matter how many documents share it. Clients can compare it to find
+	// duplicates.
+	ContentHash string `db:"content_hash" json:"content_hash"`
+	// Name of the Chroma lexer the document is highlighted with, either chosen by
+	// the uploader or detected when it was created. Empty for custom documents.
+	Language string `db:"language" json:"language"`
+	// MIME type of the content. Empty for documents from before types were
+	// recorded, which are all text.
+	ContentType string `db:"content_type" json:"content_type"`
+	// Size of the content in bytes. For documents with several files, it's the
+	// size of all of them together.
+	Size int64 `db:"size" json:"size"`
+	// Files of a document uploaded with several, in order. Nil for documents
+	// with a single, unnamed file.
+	Files []File `db:"-" json:"files,omitempty"`
+}
+
+// Revision is a snapshot of a document's content. Revision 1 is the content the
+// document was created with, and every edit adds the next one.
+type Revision struct {
+	DocumentID string    `db:"document_id" json:"document_id"`
+	Revision   int       `db:"revision" json:"revision"`
+	Content    string    `db:"content" json:"content"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+
+	ContentHash string `db:"content_hash" json:"content_hash"` // See Document.ContentHash
+}
+
+// HashContent returns the hash content is stored under.
+func HashContent(content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// documentColumns lists the columns of documentsTable in the order scanDocument
+// expects them. Documents saved before content was shared keep their own.
+const documentColumns = "d.id, COALESCE(c.content, d.content), d.created_at, d.updated_at, d.expires_at, " +
+	"d.burn_after_read, d.delete_token_hash, d.edit_token_hash, d.content_hash, d.language, d.content_type, " +
+	"COALESCE(c.encoding, ''), " + documentSize
+
+// documentSize is the size of a document in documentsTable: the total of its
+// files if it has several, otherwise that of its content.
+const documentSize = "COALESCE((SELECT SUM(fc.size) FROM document_files f JOIN contents fc ON " +
+	"fc.hash = f.content_hash WHERE f.document_id = d.id), c.size, LENGTH(d.content))"
+
+// documentsTable joins documents (as d) with their content.
+const documentsTable = "documents d LEFT JOIN contents c ON c.hash = d.content_hash"
+
+// scanner is implemented by both *sql.Row and *sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanDocument reads a row selected with documentColumns into a Document.
+func scanDocument(row scanner) (Document, error) {
+	var doc Document
+	var encoding string
+
+	err := row.Scan(&doc.ID, &doc.Content, &doc.CreatedAt, &doc.UpdatedAt, &doc.ExpiresAt, &doc.BurnAfterRead,
+		&doc.DeleteTokenHash, &doc.EditTokenHash, &doc.ContentHash, &doc.Language, &doc.ContentType, &encoding,
+		&doc.Size)
+
+	if err != nil {
+		return doc, err
+	}
+
+	if doc.ContentHash == "" {
+		doc.ContentHash = HashContent(doc.Content)
+	}
+
+	doc.Content, err = decodeContent(doc.Content, encoding)
+	return doc, err
+}
+
+// revisionColumns lists the columns of revisionsTable in the order scanRevision
+// expects them.
+const revisionColumns = "r.document_id, r.revision, COALESCE(c.content, r.content), r.created_at, r.content_hash, " +
+	"COALESCE(c.encoding, '')"
+
+// revisionsTable joins document_revisions (as r) with their content.
+const revisionsTable = "document_revisions r LEFT JOIN contents c ON c.hash = r.content_hash"
+
+// scanRevision reads a row selected with revisionColumns into a Revision.
+func scanRevision(row scanner) (Revision, error) {
+	var rev Revision
+	var encoding string
+
+	err := row.Scan(&rev.DocumentID, &rev.Revision, &rev.Content, &rev.CreatedAt, &rev.ContentHash, &encoding)
+
+	if err != nil {
+		return rev, err
+	}
+
+	if rev.ContentHash == "" {
+		rev.ContentHash = HashContent(rev.Content)
+	}
+
+	rev.Content, err = decodeContent(rev.Content, encoding)
+	return rev, err
 }
 
 //go:generate go run github.com/maxbrunsfeld/counterfeiter/v6 . Database
 type Database interface {
+	// Migrate applies every pending schema migration.
 	Migrate(ctx context.Context) error
+	// MigrateDown reverts the latest steps applied migrations.
+	MigrateDown(ctx context.Context, steps int) error
+	MigrationStatus(ctx context.Context) ([]MigrationStatus, error)
 	Close() error
 
 	GetDocument(ctx context.Context, id string) (Document, error)
-	CreateDocument(ctx context.Context, id, content string) error
+
+	// CreateDocument saves a new document, along with its files. Content is
+	// only stored if no other document, revision or file already uses it.
+	CreateDocument(ctx context.Context, document Document) error
+
+	// UpsertDocument creates a document that never expires, or replaces the
+	// content of an existing document with the same ID. Only the document's ID,
+	// Content and ContentHash are used.
+	UpsertDocument(ctx context.Context, document Document) error
+
+	// UpdateDocument replaces the content of a document and saves it as the
+	// document's next revision. Only the document's ID, Content and ContentHash
+	// are used. It returns sql.ErrNoRows if the document doesn't exist.
+	UpdateDocument(ctx context.Context, document Document) (Revision, error)
+
+	// GetRevisions lists every revision of a document, oldest first.
+	GetRevisions(ctx context.Context, id string) ([]Revision, error)
+	GetRevision(ctx context.Context, id string, revision int) (Revision, error)
+
+	// DeleteDocument deletes a document and its revisions, returning sql.ErrNoRows if it doesn't exist.
+	DeleteDocument(ctx context.Context, id string) error
+
+	// GetContent returns the content stored under a hash, and how many
+	// documents and revisions use it.
+	GetContent(ctx context.Context, hash string) (Content, error)
+
+	// GetAndDeleteDocument retrieves a document and deletes it in the same
+	// transaction, so that only one caller can ever receive its content.
+	GetAndDeleteDocument(ctx context.Context, id string) (Document, error)
+
+	// DeleteExpiredDocuments removes every document whose expiry time is at or
+	// before now. It returns the number of documents removed.
+	DeleteExpiredDocuments(ctx context.Context, now time.Time) (int64, error)
+
+	// ListDocuments lists the documents matching a filter, oldest first. Their
+	// content and files are left out.
+	ListDocuments(ctx context.Context, filter DocumentFilter) ([]Document, error)
+
+	// CountDocuments counts the documents matching a filter, and their total size.
+	CountDocuments(ctx context.Context, filter DocumentFilter) (DocumentCount, error)
+
+	// DeleteDocuments removes every document matching a filter, along with their
+	// revisions and files. It returns the number of documents removed.
+	DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error)
+
+	// ImportDocument saves a document exported from another database as it was,
+	// keeping its timestamps, tokens, revisions and files. If a document with
+	// the same ID exists, it's replaced when overwrite is set, and
+	// ErrDocumentExists is returned otherwise.
+	ImportDocument(ctx context.Context, document Document, revisions []Revision, overwrite bool) error
 }
@@ -19,6 +19,7 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net/url"
 	"strings"
 	"time"
@@ -42,38 +43,312 @@ func NewMySQL(uri *url.URL) (Database, error) {
 }
 
 func (m *MySQL) Migrate(ctx context.Context) error {
-	_, err := m.Exec(`
-CREATE TABLE IF NOT EXISTS documents (
-	id VARCHAR(255) PRIMARY KEY,
-	content TEXT NOT NULL,
-	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
-)`)
+	return migrateUp(ctx, m.DB, mysqlDialect)
+}
+
+func (m *MySQL) MigrateDown(ctx context.Context, steps int) error {
+	return migrateDown(ctx, m.DB, mysqlDialect, steps)
+}
 
-	return err
+func (m *MySQL) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return migrationStatus(ctx, m.DB, mysqlDialect)
 }
 
 func (m *MySQL) GetDocument(ctx context.Context, id string) (Document, error) {
-	doc := new(Document)
-	row := m.QueryRow("SELECT * FROM documents WHERE id=?", id)
-	err := row.Scan(&doc.ID, &doc.Content, &doc.CreatedAt, &doc.UpdatedAt)
+	row := m.QueryRow("SELECT "+documentColumns+" FROM "+documentsTable+" WHERE d.id=?", id)
 
-	return *doc, err
+	doc, err := scanDocument(row)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Files, err = getFiles(ctx, m.DB, mysqlDialect, id)
+	return doc, err
 }
 
-func (m *MySQL) CreateDocument(ctx context.Context, id, content string) error {
+func (m *MySQL) CreateDocument(ctx context.Context, document Document) error {
 	tx, err := m.Begin()
 
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec("INSERT INTO documents (id, content) VALUES (?, ?)",
-		id, content) // created_at and updated_at are auto-generated
+	defer tx.Rollback()
+
+	hash, err := holdContent(ctx, tx, mysqlDialect, document)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO documents (id, content, expires_at, burn_after_read, delete_token_hash, edit_token_hash,
+	content_hash, language, content_type) VALUES (?, '', ?, ?, ?, ?, ?, ?, ?)`,
+		document.ID, document.ExpiresAt, document.BurnAfterRead, document.DeleteTokenHash, document.EditTokenHash, hash,
+		document.Language, document.ContentType) // created_at and updated_at are auto-generated
+
+	if err != nil {
+		return err
+	}
+
+	// The content a document is created with is its first revision
+	if _, err := m.addRevision(ctx, tx, document); err != nil {
+		return err
+	}
+
+	if err := addFiles(ctx, tx, mysqlDialect, document); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *MySQL) UpsertDocument(ctx context.Context, document Document) error {
+	tx, err := m.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var previous string
+
+	// Lock the document if it exists, so its previous content can be released
+	err = tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id=? FOR UPDATE",
+		document.ID).Scan(&previous)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	hash, err := holdContent(ctx, tx, mysqlDialect, document)
+
+	if err != nil {
+		return err
+	}
+
+	// updated_at is only changed automatically if a column is, so set it explicitly
+	_, err = tx.ExecContext(ctx, `INSERT INTO documents (id, content, content_hash) VALUES (?, '', ?)
+ON DUPLICATE KEY UPDATE content = '', content_hash = VALUES(content_hash), updated_at = CURRENT_TIMESTAMP,
+	expires_at = NULL, burn_after_read = FALSE, delete_token_hash = '', edit_token_hash = '', language = '',
+	content_type = ''`, document.ID, hash)
+
+	if err != nil {
+		return err
+	}
+
+	if err := releaseContent(ctx, tx, mysqlDialect, previous); err != nil {
+		return err
+	}
+
+	// The document replaced may have been uploaded with several files
+	if err := deleteFiles(ctx, tx, mysqlDialect, document.ID); err != nil {
+		return err
+	}
+
+	if _, err := m.addRevision(ctx, tx, document); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *MySQL) UpdateDocument(ctx context.Context, document Document) (Revision, error) {
+	tx, err := m.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	defer tx.Rollback()
+
+	// Lock the document so concurrent edits get consecutive revision numbers
+	var previous string
+
+	if err := tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id=? FOR UPDATE",
+		document.ID).Scan(&previous); err != nil {
+		return Revision{}, err
+	}
+
+	hash, err := holdContent(ctx, tx, mysqlDialect, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	// updated_at is only changed automatically if the content is, so set it explicitly
+	if _, err := tx.ExecContext(ctx, "UPDATE documents SET content='', content_hash=?, updated_at=CURRENT_TIMESTAMP "+
+		"WHERE id=?", hash, document.ID); err != nil {
+		return Revision{}, err
+	}
+
+	if err := releaseContent(ctx, tx, mysqlDialect, previous); err != nil {
+		return Revision{}, err
+	}
+
+	rev, err := m.addRevision(ctx, tx, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	return rev, tx.Commit()
+}
+
+// addRevision saves a document's content as its next revision. The document's
+// row must already be locked by tx.
+func (m *MySQL) addRevision(ctx context.Context, tx *sql.Tx, document Document) (Revision, error) {
+	hash, err := holdContent(ctx, tx, mysqlDialect, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	rev := Revision{DocumentID: document.ID, Content: document.Content, ContentHash: hash}
+
+	// Use a locking read, so the latest committed revisions are seen rather than a snapshot
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(revision), 0) + 1 FROM document_revisions "+
+		"WHERE document_id=? FOR UPDATE", document.ID).Scan(&rev.Revision); err != nil {
+		return Revision{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "INSERT INTO document_revisions (document_id, revision, content, content_hash) "+
+		"VALUES (?, ?, '', ?)", document.ID, rev.Revision, hash); err != nil {
+		return Revision{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, "SELECT created_at FROM document_revisions WHERE document_id=? AND revision=?",
+		document.ID, rev.Revision).Scan(&rev.CreatedAt)
+
+	return rev, err
+}
+
+func (m *MySQL) GetRevisions(ctx context.Context, id string) ([]Revision, error) {
+	rows, err := m.QueryContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionsTable+" WHERE r.document_id=? "+
+		"ORDER BY r.revision", id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []Revision{}
+
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (m *MySQL) GetRevision(ctx context.Context, id string, revision int) (Revision, error) {
+	row := m.QueryRowContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionsTable+" "+
+		"WHERE r.document_id=? AND r.revision=?", id, revision)
+
+	return scanRevision(row)
+}
+
+func (m *MySQL) GetContent(ctx context.Context, hash string) (Content, error) {
+	return getContent(ctx, m.DB, mysqlDialect, hash)
+}
+
+func (m *MySQL) DeleteDocument(ctx context.Context, id string) error {
+	tx, err := m.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := deleteDocument(ctx, tx, mysqlDialect, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (m *MySQL) GetAndDeleteDocument(ctx context.Context, id string) (Document, error) {
+	tx, err := m.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	defer tx.Rollback()
+
+	// Lock the row so concurrent readers wait for this transaction, then find it gone
+	row := tx.QueryRowContext(ctx, "SELECT "+documentColumns+" FROM "+documentsTable+" WHERE d.id=? FOR UPDATE", id)
+
+	doc, err := scanDocument(row)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Files, err = getFiles(ctx, tx, mysqlDialect, id)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	if err := deleteDocument(ctx, tx, mysqlDialect, id); err != nil {
+		return Document{}, err
+	}
+
+	return doc, tx.Commit()
+}
+
+func (m *MySQL) ListDocuments(ctx context.Context, filter DocumentFilter) ([]Document, error) {
+	return listDocuments(ctx, m.DB, mysqlDialect, filter)
+}
+
+func (m *MySQL) CountDocuments(ctx context.Context, filter DocumentFilter) (DocumentCount, error) {
+	return countDocuments(ctx, m.DB, mysqlDialect, filter)
+}
+
+func (m *MySQL) DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error) {
+	tx, err := m.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	n, err := deleteDocuments(ctx, tx, mysqlDialect, filter)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+func (m *MySQL) DeleteExpiredDocuments(ctx context.Context, now time.Time) (int64, error) {
+	return m.DeleteDocuments(ctx, DocumentFilter{ExpiredBy: now})
+}
+
+func (m *MySQL) ImportDocument(ctx context.Context, document Document, revisions []Revision, overwrite bool) error {
+	tx, err := m.BeginTx(ctx, nil)
 
 	if err != nil {
 		return err
 	}
 
+	defer tx.Rollback()
+
+	if err := importDocument(ctx, tx, mysqlDialect, document, revisions, overwrite); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
@@ -19,7 +19,9 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net/url"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -35,38 +37,305 @@ func NewPostgres(uri *url.URL) (Database, error) {
 }
 
 func (p *Postgres) Migrate(ctx context.Context) error {
-	_, err := p.Exec(`
-CREATE TABLE IF NOT EXISTS documents (
-	id varchar(255) PRIMARY KEY,
-	content text NOT NULL,
-	created_at timestamp with time zone DEFAULT now(),
-	updated_at timestamp with time zone DEFAULT now()
-)`)
+	return migrateUp(ctx, p.DB, postgresDialect)
+}
+
+func (p *Postgres) MigrateDown(ctx context.Context, steps int) error {
+	return migrateDown(ctx, p.DB, postgresDialect, steps)
+}
 
-	return err
+func (p *Postgres) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	return migrationStatus(ctx, p.DB, postgresDialect)
 }
 
 func (p *Postgres) GetDocument(ctx context.Context, id string) (Document, error) {
-	doc := new(Document)
-	row := p.QueryRow("SELECT * FROM documents WHERE id=$1", id)
-	err := row.Scan(&doc.ID, &doc.Content, &doc.CreatedAt, &doc.UpdatedAt)
+	row := p.QueryRow("SELECT "+documentColumns+" FROM "+documentsTable+" WHERE d.id=$1", id)
+
+	doc, err := scanDocument(row)
+
+	if err != nil {
+		return Document{}, err
+	}
 
-	return *doc, err
+	doc.Files, err = getFiles(ctx, p.DB, postgresDialect, id)
+	return doc, err
 }
 
-func (p *Postgres) CreateDocument(ctx context.Context, id, content string) error {
+func (p *Postgres) CreateDocument(ctx context.Context, document Document) error {
 	tx, err := p.Begin()
 
 	if err != nil {
 		return err
 	}
 
-	_, err = tx.Exec("INSERT INTO documents (id, content) VALUES ($1, $2)",
-		id, content) // created_at and updated_at are auto-generated
+	defer tx.Rollback()
+
+	hash, err := holdContent(ctx, tx, postgresDialect, document)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO documents (id, content, expires_at, burn_after_read, delete_token_hash, edit_token_hash,
+	content_hash, language, content_type) VALUES ($1, '', $2, $3, $4, $5, $6, $7, $8)`,
+		document.ID, document.ExpiresAt, document.BurnAfterRead, document.DeleteTokenHash, document.EditTokenHash, hash,
+		document.Language, document.ContentType) // created_at and updated_at are auto-generated
+
+	if err != nil {
+		return err
+	}
+
+	// The content a document is created with is its first revision
+	if _, err := p.addRevision(ctx, tx, document); err != nil {
+		return err
+	}
+
+	if err := addFiles(ctx, tx, postgresDialect, document); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p *Postgres) UpsertDocument(ctx context.Context, document Document) error {
+	tx, err := p.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var previous string
+
+	// Lock the document if it exists, so its previous content can be released
+	err = tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id=$1 FOR UPDATE",
+		document.ID).Scan(&previous)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	hash, err := holdContent(ctx, tx, postgresDialect, document)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO documents (id, content, content_hash) VALUES ($1, '', $2)
+ON CONFLICT (id) DO UPDATE SET content = '', content_hash = EXCLUDED.content_hash, updated_at = now(),
+	expires_at = NULL, burn_after_read = false, delete_token_hash = '', edit_token_hash = '', language = '',
+	content_type = ''`, document.ID, hash)
+
+	if err != nil {
+		return err
+	}
+
+	if err := releaseContent(ctx, tx, postgresDialect, previous); err != nil {
+		return err
+	}
+
+	// The document replaced may have been uploaded with several files
+	if err := deleteFiles(ctx, tx, postgresDialect, document.ID); err != nil {
+		return err
+	}
+
+	if _, err := p.addRevision(ctx, tx, document); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p *Postgres) UpdateDocument(ctx context.Context, document Document) (Revision, error) {
+	tx, err := p.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	defer tx.Rollback()
+
+	// Lock the document so concurrent edits get consecutive revision numbers
+	var previous string
+
+	if err := tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id=$1 FOR UPDATE",
+		document.ID).Scan(&previous); err != nil {
+		return Revision{}, err
+	}
+
+	hash, err := holdContent(ctx, tx, postgresDialect, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE documents SET content='', content_hash=$1, updated_at=now() WHERE id=$2",
+		hash, document.ID); err != nil {
+		return Revision{}, err
+	}
+
+	if err := releaseContent(ctx, tx, postgresDialect, previous); err != nil {
+		return Revision{}, err
+	}
+
+	rev, err := p.addRevision(ctx, tx, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	return rev, tx.Commit()
+}
+
+// addRevision saves a document's content as its next revision. The document's
+// row must already be locked by tx.
+func (p *Postgres) addRevision(ctx context.Context, tx *sql.Tx, document Document) (Revision, error) {
+	hash, err := holdContent(ctx, tx, postgresDialect, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	rev := Revision{DocumentID: document.ID, Content: document.Content, ContentHash: hash}
+
+	if err := tx.QueryRowContext(ctx, "SELECT COALESCE(MAX(revision), 0) + 1 FROM document_revisions "+
+		"WHERE document_id=$1", document.ID).Scan(&rev.Revision); err != nil {
+		return Revision{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO document_revisions (document_id, revision, content, content_hash) "+
+		"VALUES ($1, $2, '', $3) RETURNING created_at", document.ID, rev.Revision, hash).Scan(&rev.CreatedAt)
+
+	return rev, err
+}
+
+func (p *Postgres) GetRevisions(ctx context.Context, id string) ([]Revision, error) {
+	rows, err := p.QueryContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionsTable+" WHERE r.document_id=$1 "+
+		"ORDER BY r.revision", id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []Revision{}
+
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (p *Postgres) GetRevision(ctx context.Context, id string, revision int) (Revision, error) {
+	row := p.QueryRowContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionsTable+" "+
+		"WHERE r.document_id=$1 AND r.revision=$2", id, revision)
+
+	return scanRevision(row)
+}
+
+func (p *Postgres) GetContent(ctx context.Context, hash string) (Content, error) {
+	return getContent(ctx, p.DB, postgresDialect, hash)
+}
+
+func (p *Postgres) DeleteDocument(ctx context.Context, id string) error {
+	tx, err := p.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := deleteDocument(ctx, tx, postgresDialect, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (p *Postgres) GetAndDeleteDocument(ctx context.Context, id string) (Document, error) {
+	tx, err := p.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	defer tx.Rollback()
+
+	// Lock the row so concurrent readers wait for this transaction, then find it gone
+	row := tx.QueryRowContext(ctx, "SELECT "+documentColumns+" FROM "+documentsTable+" WHERE d.id=$1 FOR UPDATE OF d",
+		id)
+
+	doc, err := scanDocument(row)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Files, err = getFiles(ctx, tx, postgresDialect, id)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	if err := deleteDocument(ctx, tx, postgresDialect, id); err != nil {
+		return Document{}, err
+	}
+
+	return doc, tx.Commit()
+}
+
+func (p *Postgres) ListDocuments(ctx context.Context, filter DocumentFilter) ([]Document, error) {
+	return listDocuments(ctx, p.DB, postgresDialect, filter)
+}
+
+func (p *Postgres) CountDocuments(ctx context.Context, filter DocumentFilter) (DocumentCount, error) {
+	return countDocuments(ctx, p.DB, postgresDialect, filter)
+}
+
+func (p *Postgres) DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error) {
+	tx, err := p.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	n, err := deleteDocuments(ctx, tx, postgresDialect, filter)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+func (p *Postgres) DeleteExpiredDocuments(ctx context.Context, now time.Time) (int64, error) {
+	return p.DeleteDocuments(ctx, DocumentFilter{ExpiredBy: now})
+}
+
+func (p *Postgres) ImportDocument(ctx context.Context, document Document, revisions []Revision, overwrite bool) error {
+	tx, err := p.BeginTx(ctx, nil)
 
 	if err != nil {
 		return err
 	}
 
+	defer tx.Rollback()
+
+	if err := importDocument(ctx, tx, postgresDialect, document, revisions, overwrite); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
@@ -19,8 +19,10 @@ package database
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"net/url"
 	"sync"
+	"time"
 
 	_ "modernc.org/sqlite"
 )
@@ -37,29 +39,43 @@ func NewSQLite(uri *url.URL) (Database, error) {
 }
 
 func (s *SQLite) Migrate(ctx context.Context) error {
-	_, err := s.Exec(`
-CREATE TABLE IF NOT EXISTS documents (
-    id TEXT PRIMARY KEY,
-    content TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    usdated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);`)
+	s.Lock()
+	defer s.Unlock()
+
+	return migrateUp(ctx, s.DB, sqliteDialect)
+}
+
+func (s *SQLite) MigrateDown(ctx context.Context, steps int) error {
+	s.Lock()
+	defer s.Unlock()
 
-	return err
+	return migrateDown(ctx, s.DB, sqliteDialect, steps)
+}
+
+func (s *SQLite) MigrationStatus(ctx context.Context) ([]MigrationStatus, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	return migrationStatus(ctx, s.DB, sqliteDialect)
 }
 
 func (s *SQLite) GetDocument(ctx context.Context, id string) (Document, error) {
 	s.RLock()
 	defer s.RUnlock()
 
-	doc := new(Document)
-	row := s.QueryRow("SELECT * FROM documents WHERE id=$1", id)
-	err := row.Scan(&doc.ID, &doc.Content, &doc.CreatedAt, &doc.UpdatedAt)
+	row := s.QueryRow("SELECT "+documentColumns+" FROM "+documentsTable+" WHERE d.id=$1", id)
+
+	doc, err := scanDocument(row)
 
-	return *doc, err
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Files, err = getFiles(ctx, s.DB, sqliteDialect, id)
+	return doc, err
 }
 
-func (s *SQLite) CreateDocument(ctx context.Context, id, content string) error {
+func (s *SQLite) CreateDocument(ctx context.Context, document Document) error {
 	s.Lock()
 	defer s.Unlock()
 
@@ -69,10 +85,297 @@ func (s *SQLite) CreateDocument(ctx context.Context, id, content string) error {
 		return err
 	}
 
-	_, err = tx.Exec("INSERT INTO documents (id, content) VALUES ($1, $2)",
-		id, content) // created_at and updated_at are auto-generated
+	defer tx.Rollback()
+
+	hash, err := holdContent(ctx, tx, sqliteDialect, document)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`INSERT INTO documents (id, content, expires_at, burn_after_read, delete_token_hash, edit_token_hash,
+	content_hash, language, content_type) VALUES ($1, '', $2, $3, $4, $5, $6, $7, $8)`,
+		document.ID, document.ExpiresAt, document.BurnAfterRead, document.DeleteTokenHash, document.EditTokenHash, hash,
+		document.Language, document.ContentType) // created_at and updated_at are auto-generated
+
+	if err != nil {
+		return err
+	}
+
+	// The content a document is created with is its first revision
+	if _, err := s.addRevision(ctx, tx, document); err != nil {
+		return err
+	}
+
+	if err := addFiles(ctx, tx, sqliteDialect, document); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLite) UpsertDocument(ctx context.Context, document Document) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var previous string
+
+	// The previous content of an existing document is released once it's replaced
+	err = tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id=$1", document.ID).Scan(&previous)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	hash, err := holdContent(ctx, tx, sqliteDialect, document)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, `INSERT INTO documents (id, content, content_hash) VALUES ($1, '', $2)
+ON CONFLICT (id) DO UPDATE SET content = '', content_hash = excluded.content_hash,
+	updated_at = CURRENT_TIMESTAMP, expires_at = NULL, burn_after_read = 0, delete_token_hash = '',
+	edit_token_hash = '', language = '', content_type = ''`, document.ID, hash)
+
+	if err != nil {
+		return err
+	}
+
+	if err := releaseContent(ctx, tx, sqliteDialect, previous); err != nil {
+		return err
+	}
+
+	// The document replaced may have been uploaded with several files
+	if err := deleteFiles(ctx, tx, sqliteDialect, document.ID); err != nil {
+		return err
+	}
+
+	if _, err := s.addRevision(ctx, tx, document); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLite) UpdateDocument(ctx context.Context, document Document) (Revision, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	defer tx.Rollback()
+
+	var previous string
+
+	if err := tx.QueryRowContext(ctx, "SELECT content_hash FROM documents WHERE id=$1",
+		document.ID).Scan(&previous); err != nil {
+		return Revision{}, err
+	}
+
+	hash, err := holdContent(ctx, tx, sqliteDialect, document)
 
 	if err != nil {
+		return Revision{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, "UPDATE documents SET content='', content_hash=$1, updated_at=CURRENT_TIMESTAMP "+
+		"WHERE id=$2", hash, document.ID); err != nil {
+		return Revision{}, err
+	}
+
+	if err := releaseContent(ctx, tx, sqliteDialect, previous); err != nil {
+		return Revision{}, err
+	}
+
+	rev, err := s.addRevision(ctx, tx, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	return rev, tx.Commit()
+}
+
+// addRevision saves a document's content as its next revision. The caller
+// must hold the write lock.
+func (s *SQLite) addRevision(ctx context.Context, tx *sql.Tx, document Document) (Revision, error) {
+	hash, err := holdContent(ctx, tx, sqliteDialect, document)
+
+	if err != nil {
+		return Revision{}, err
+	}
+
+	rev := Revision{DocumentID: document.ID, Content: document.Content, ContentHash: hash}
+	err = tx.QueryRowContext(ctx, `INSERT INTO document_revisions (document_id, revision, content, content_hash)
+SELECT $1, COALESCE(MAX(revision), 0) + 1, '', $2 FROM document_revisions WHERE document_id=$1
+RETURNING revision, created_at`, document.ID, hash).Scan(&rev.Revision, &rev.CreatedAt)
+
+	return rev, err
+}
+
+func (s *SQLite) GetRevisions(ctx context.Context, id string) ([]Revision, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	rows, err := s.QueryContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionsTable+" WHERE r.document_id=$1 "+
+		"ORDER BY r.revision", id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []Revision{}
+
+	for rows.Next() {
+		rev, err := scanRevision(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	return revisions, rows.Err()
+}
+
+func (s *SQLite) GetRevision(ctx context.Context, id string, revision int) (Revision, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	row := s.QueryRowContext(ctx, "SELECT "+revisionColumns+" FROM "+revisionsTable+" "+
+		"WHERE r.document_id=$1 AND r.revision=$2", id, revision)
+
+	return scanRevision(row)
+}
+
+func (s *SQLite) GetContent(ctx context.Context, hash string) (Content, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return getContent(ctx, s.DB, sqliteDialect, hash)
+}
+
+func (s *SQLite) DeleteDocument(ctx context.Context, id string) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := deleteDocument(ctx, tx, sqliteDialect, id); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLite) GetAndDeleteDocument(ctx context.Context, id string) (Document, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.BeginTx(ctx, nil)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	defer tx.Rollback()
+
+	row := tx.QueryRowContext(ctx, "SELECT "+documentColumns+" FROM "+documentsTable+" WHERE d.id=$1", id)
+
+	doc, err := scanDocument(row)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	doc.Files, err = getFiles(ctx, tx, sqliteDialect, id)
+
+	if err != nil {
+		return Document{}, err
+	}
+
+	// SQLite has no row locks; if another connection got here first, there's nothing to delete
+	if err := deleteDocument(ctx, tx, sqliteDialect, id); err != nil {
+		return Document{}, err
+	}
+
+	return doc, tx.Commit()
+}
+
+func (s *SQLite) ListDocuments(ctx context.Context, filter DocumentFilter) ([]Document, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return listDocuments(ctx, s.DB, sqliteDialect, filter)
+}
+
+func (s *SQLite) CountDocuments(ctx context.Context, filter DocumentFilter) (DocumentCount, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	return countDocuments(ctx, s.DB, sqliteDialect, filter)
+}
+
+func (s *SQLite) DeleteDocuments(ctx context.Context, filter DocumentFilter) (int64, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.BeginTx(ctx, nil)
+
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	n, err := deleteDocuments(ctx, tx, sqliteDialect, filter)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
+
+func (s *SQLite) DeleteExpiredDocuments(ctx context.Context, now time.Time) (int64, error) {
+	return s.DeleteDocuments(ctx, DocumentFilter{ExpiredBy: now})
+}
+
+func (s *SQLite) ImportDocument(ctx context.Context, document Document, revisions []Revision, overwrite bool) error {
+	s.Lock()
+	defer s.Unlock()
+
+	tx, err := s.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := importDocument(ctx, tx, sqliteDialect, document, revisions, overwrite); err != nil {
 		return err
 	}
 
@@ -4,6 +4,7 @@ package databasefakes
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/lukewhrit/spacebin/internal/database"
 )
@@ -19,12 +20,25 @@ type FakeDatabase struct {
 	closeReturnsOnCall map[int]struct {
 		result1 error
 	}
-	CreateDocumentStub        func(context.Context, string, string) error
+	CountDocumentsStub        func(context.Context, database.DocumentFilter) (database.DocumentCount, error)
+	countDocumentsMutex       sync.RWMutex
+	countDocumentsArgsForCall []struct {
+		arg1 context.Context
+		arg2 database.DocumentFilter
+	}
+	countDocumentsReturns struct {
+		result1 database.DocumentCount
+		result2 error
+	}
+	countDocumentsReturnsOnCall map[int]struct {
+		result1 database.DocumentCount
+		result2 error
+	}
+	CreateDocumentStub        func(context.Context, database.Document) error
 	createDocumentMutex       sync.RWMutex
 	createDocumentArgsForCall []struct {
 		arg1 context.Context
-		arg2 string
-		arg3 string
+		arg2 database.Document
 	}
 	createDocumentReturns struct {
 		result1 error
@@ -32,6 +46,74 @@ type FakeDatabase struct {
 	createDocumentReturnsOnCall map[int]struct {
 		result1 error
 	}
+	DeleteDocumentStub        func(context.Context, string) error
+	deleteDocumentMutex       sync.RWMutex
+	deleteDocumentArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	deleteDocumentReturns struct {
+		result1 error
+	}
+	deleteDocumentReturnsOnCall map[int]struct {
+		result1 error
+	}
+	DeleteDocumentsStub        func(context.Context, database.DocumentFilter) (int64, error)
+	deleteDocumentsMutex       sync.RWMutex
+	deleteDocumentsArgsForCall []struct {
+		arg1 context.Context
+		arg2 database.DocumentFilter
+	}
+	deleteDocumentsReturns struct {
+		result1 int64
+		result2 error
+	}
+	deleteDocumentsReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
+	DeleteExpiredDocumentsStub        func(context.Context, time.Time) (int64, error)
+	deleteExpiredDocumentsMutex       sync.RWMutex
+	deleteExpiredDocumentsArgsForCall []struct {
+		arg1 context.Context
+		arg2 time.Time
+	}
+	deleteExpiredDocumentsReturns struct {
+		result1 int64
+		result2 error
+	}
+	deleteExpiredDocumentsReturnsOnCall map[int]struct {
+		result1 int64
+		result2 error
+	}
+	GetAndDeleteDocumentStub        func(context.Context, string) (database.Document, error)
+	getAndDeleteDocumentMutex       sync.RWMutex
+	getAndDeleteDocumentArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getAndDeleteDocumentReturns struct {
+		result1 database.Document
+		result2 error
+	}
+	getAndDeleteDocumentReturnsOnCall map[int]struct {
+		result1 database.Document
+		result2 error
+	}
+	GetContentStub        func(context.Context, string) (database.Content, error)
+	getContentMutex       sync.RWMutex
+	getContentArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getContentReturns struct {
+		result1 database.Content
+		result2 error
+	}
+	getContentReturnsOnCall map[int]struct {
+		result1 database.Content
+		result2 error
+	}
 	GetDocumentStub        func(context.Context, string) (database.Document, error)
 	getDocumentMutex       sync.RWMutex
 	getDocumentArgsForCall []struct {
@@ -46,6 +128,63 @@ type FakeDatabase struct {
 		result1 database.Document
 		result2 error
 	}
+	GetRevisionStub        func(context.Context, string, int) (database.Revision, error)
+	getRevisionMutex       sync.RWMutex
+	getRevisionArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}
+	getRevisionReturns struct {
+		result1 database.Revision
+		result2 error
+	}
+	getRevisionReturnsOnCall map[int]struct {
+		result1 database.Revision
+		result2 error
+	}
+	GetRevisionsStub        func(context.Context, string) ([]database.Revision, error)
+	getRevisionsMutex       sync.RWMutex
+	getRevisionsArgsForCall []struct {
+		arg1 context.Context
+		arg2 string
+	}
+	getRevisionsReturns struct {
+		result1 []database.Revision
+		result2 error
+	}
+	getRevisionsReturnsOnCall map[int]struct {
+		result1 []database.Revision
+		result2 error
+	}
+	ImportDocumentStub        func(context.Context, database.Document, []database.Revision, bool) error
+	importDocumentMutex       sync.RWMutex
+	importDocumentArgsForCall []struct {
+		arg1 context.Context
+		arg2 database.Document
+		arg3 []database.Revision
+		arg4 bool
+	}
+	importDocumentReturns struct {
+		result1 error
+	}
+	importDocumentReturnsOnCall map[int]struct {
+		result1 error
+	}
+	ListDocumentsStub        func(context.Context, database.DocumentFilter) ([]database.Document, error)
+	listDocumentsMutex       sync.RWMutex
+	listDocumentsArgsForCall []struct {
+		arg1 context.Context
+		arg2 database.DocumentFilter
+	}
+	listDocumentsReturns struct {
+		result1 []database.Document
+		result2 error
+	}
+	listDocumentsReturnsOnCall map[int]struct {
+		result1 []database.Document
+		result2 error
+	}
 	MigrateStub        func(context.Context) error
 	migrateMutex       sync.RWMutex
 	migrateArgsForCall []struct {
@@ -57,6 +196,57 @@ type FakeDatabase struct {
 	migrateReturnsOnCall map[int]struct {
 		result1 error
 	}
+	MigrateDownStub        func(context.Context, int) error
+	migrateDownMutex       sync.RWMutex
+	migrateDownArgsForCall []struct {
+		arg1 context.Context
+		arg2 int
+	}
+	migrateDownReturns struct {
+		result1 error
+	}
+	migrateDownReturnsOnCall map[int]struct {
+		result1 error
+	}
+	MigrationStatusStub        func(context.Context) ([]database.MigrationStatus, error)
+	migrationStatusMutex       sync.RWMutex
+	migrationStatusArgsForCall []struct {
+		arg1 context.Context
+	}
+	migrationStatusReturns struct {
+		result1 []database.MigrationStatus
+		result2 error
+	}
+	migrationStatusReturnsOnCall map[int]struct {
+		result1 []database.MigrationStatus
+		result2 error
+	}
+	UpdateDocumentStub        func(context.Context, database.Document) (database.Revision, error)
+	updateDocumentMutex       sync.RWMutex
+	updateDocumentArgsForCall []struct {
+		arg1 context.Context
+		arg2 database.Document
+	}
+	updateDocumentReturns struct {
+		result1 database.Revision
+		result2 error
+	}
+	updateDocumentReturnsOnCall map[int]struct {
+		result1 database.Revision
+		result2 error
+	}
+	UpsertDocumentStub        func(context.Context, database.Document) error
+	upsertDocumentMutex       sync.RWMutex
+	upsertDocumentArgsForCall []struct {
+		arg1 context.Context
+		arg2 database.Document
+	}
+	upsertDocumentReturns struct {
+		result1 error
+	}
+	upsertDocumentReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
@@ -114,20 +304,84 @@ func (fake *FakeDatabase) CloseReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
-func (fake *FakeDatabase) CreateDocument(arg1 context.Context, arg2 string, arg3 string) error {
+func (fake *FakeDatabase) CountDocuments(arg1 context.Context, arg2 database.DocumentFilter) (database.DocumentCount, error) {
+	fake.countDocumentsMutex.Lock()
+	ret, specificReturn := fake.countDocumentsReturnsOnCall[len(fake.countDocumentsArgsForCall)]
+	fake.countDocumentsArgsForCall = append(fake.countDocumentsArgsForCall, struct {
+		arg1 context.Context
+		arg2 database.DocumentFilter
+	}{arg1, arg2})
+	stub := fake.CountDocumentsStub
+	fakeReturns := fake.countDocumentsReturns
+	fake.recordInvocation("CountDocuments", []interface{}{arg1, arg2})
+	fake.countDocumentsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) CountDocumentsCallCount() int {
+	fake.countDocumentsMutex.RLock()
+	defer fake.countDocumentsMutex.RUnlock()
+	return len(fake.countDocumentsArgsForCall)
+}
+
+func (fake *FakeDatabase) CountDocumentsCalls(stub func(context.Context, database.DocumentFilter) (database.DocumentCount, error)) {
+	fake.countDocumentsMutex.Lock()
+	defer fake.countDocumentsMutex.Unlock()
+	fake.CountDocumentsStub = stub
+}
+
+func (fake *FakeDatabase) CountDocumentsArgsForCall(i int) (context.Context, database.DocumentFilter) {
+	fake.countDocumentsMutex.RLock()
+	defer fake.countDocumentsMutex.RUnlock()
+	argsForCall := fake.countDocumentsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) CountDocumentsReturns(result1 database.DocumentCount, result2 error) {
+	fake.countDocumentsMutex.Lock()
+	defer fake.countDocumentsMutex.Unlock()
+	fake.CountDocumentsStub = nil
+	fake.countDocumentsReturns = struct {
+		result1 database.DocumentCount
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) CountDocumentsReturnsOnCall(i int, result1 database.DocumentCount, result2 error) {
+	fake.countDocumentsMutex.Lock()
+	defer fake.countDocumentsMutex.Unlock()
+	fake.CountDocumentsStub = nil
+	if fake.countDocumentsReturnsOnCall == nil {
+		fake.countDocumentsReturnsOnCall = make(map[int]struct {
+			result1 database.DocumentCount
+			result2 error
+		})
+	}
+	fake.countDocumentsReturnsOnCall[i] = struct {
+		result1 database.DocumentCount
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) CreateDocument(arg1 context.Context, arg2 database.Document) error {
 	fake.createDocumentMutex.Lock()
 	ret, specificReturn := fake.createDocumentReturnsOnCall[len(fake.createDocumentArgsForCall)]
 	fake.createDocumentArgsForCall = append(fake.createDocumentArgsForCall, struct {
 		arg1 context.Context
-		arg2 string
-		arg3 string
-	}{arg1, arg2, arg3})
+		arg2 database.Document
+	}{arg1, arg2})
 	stub := fake.CreateDocumentStub
 	fakeReturns := fake.createDocumentReturns
-	fake.recordInvocation("CreateDocument", []interface{}{arg1, arg2, arg3})
+	fake.recordInvocation("CreateDocument", []interface{}{arg1, arg2})
 	fake.createDocumentMutex.Unlock()
 	if stub != nil {
-		return stub(arg1, arg2, arg3)
+		return stub(arg1, arg2)
 	}
 	if specificReturn {
 		return ret.result1
@@ -141,17 +395,17 @@ func (fake *FakeDatabase) CreateDocumentCallCount() int {
 	return len(fake.createDocumentArgsForCall)
 }
 
-func (fake *FakeDatabase) CreateDocumentCalls(stub func(context.Context, string, string) error) {
+func (fake *FakeDatabase) CreateDocumentCalls(stub func(context.Context, database.Document) error) {
 	fake.createDocumentMutex.Lock()
 	defer fake.createDocumentMutex.Unlock()
 	fake.CreateDocumentStub = stub
 }
 
-func (fake *FakeDatabase) CreateDocumentArgsForCall(i int) (context.Context, string, string) {
+func (fake *FakeDatabase) CreateDocumentArgsForCall(i int) (context.Context, database.Document) {
 	fake.createDocumentMutex.RLock()
 	defer fake.createDocumentMutex.RUnlock()
 	argsForCall := fake.createDocumentArgsForCall[i]
-	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+	return argsForCall.arg1, argsForCall.arg2
 }
 
 func (fake *FakeDatabase) CreateDocumentReturns(result1 error) {
@@ -177,6 +431,328 @@ func (fake *FakeDatabase) CreateDocumentReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeDatabase) DeleteDocument(arg1 context.Context, arg2 string) error {
+	fake.deleteDocumentMutex.Lock()
+	ret, specificReturn := fake.deleteDocumentReturnsOnCall[len(fake.deleteDocumentArgsForCall)]
+	fake.deleteDocumentArgsForCall = append(fake.deleteDocumentArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.DeleteDocumentStub
+	fakeReturns := fake.deleteDocumentReturns
+	fake.recordInvocation("DeleteDocument", []interface{}{arg1, arg2})
+	fake.deleteDocumentMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDatabase) DeleteDocumentCallCount() int {
+	fake.deleteDocumentMutex.RLock()
+	defer fake.deleteDocumentMutex.RUnlock()
+	return len(fake.deleteDocumentArgsForCall)
+}
+
+func (fake *FakeDatabase) DeleteDocumentCalls(stub func(context.Context, string) error) {
+	fake.deleteDocumentMutex.Lock()
+	defer fake.deleteDocumentMutex.Unlock()
+	fake.DeleteDocumentStub = stub
+}
+
+func (fake *FakeDatabase) DeleteDocumentArgsForCall(i int) (context.Context, string) {
+	fake.deleteDocumentMutex.RLock()
+	defer fake.deleteDocumentMutex.RUnlock()
+	argsForCall := fake.deleteDocumentArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) DeleteDocumentReturns(result1 error) {
+	fake.deleteDocumentMutex.Lock()
+	defer fake.deleteDocumentMutex.Unlock()
+	fake.DeleteDocumentStub = nil
+	fake.deleteDocumentReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) DeleteDocumentReturnsOnCall(i int, result1 error) {
+	fake.deleteDocumentMutex.Lock()
+	defer fake.deleteDocumentMutex.Unlock()
+	fake.DeleteDocumentStub = nil
+	if fake.deleteDocumentReturnsOnCall == nil {
+		fake.deleteDocumentReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.deleteDocumentReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) DeleteDocuments(arg1 context.Context, arg2 database.DocumentFilter) (int64, error) {
+	fake.deleteDocumentsMutex.Lock()
+	ret, specificReturn := fake.deleteDocumentsReturnsOnCall[len(fake.deleteDocumentsArgsForCall)]
+	fake.deleteDocumentsArgsForCall = append(fake.deleteDocumentsArgsForCall, struct {
+		arg1 context.Context
+		arg2 database.DocumentFilter
+	}{arg1, arg2})
+	stub := fake.DeleteDocumentsStub
+	fakeReturns := fake.deleteDocumentsReturns
+	fake.recordInvocation("DeleteDocuments", []interface{}{arg1, arg2})
+	fake.deleteDocumentsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) DeleteDocumentsCallCount() int {
+	fake.deleteDocumentsMutex.RLock()
+	defer fake.deleteDocumentsMutex.RUnlock()
+	return len(fake.deleteDocumentsArgsForCall)
+}
+
+func (fake *FakeDatabase) DeleteDocumentsCalls(stub func(context.Context, database.DocumentFilter) (int64, error)) {
+	fake.deleteDocumentsMutex.Lock()
+	defer fake.deleteDocumentsMutex.Unlock()
+	fake.DeleteDocumentsStub = stub
+}
+
+func (fake *FakeDatabase) DeleteDocumentsArgsForCall(i int) (context.Context, database.DocumentFilter) {
+	fake.deleteDocumentsMutex.RLock()
+	defer fake.deleteDocumentsMutex.RUnlock()
+	argsForCall := fake.deleteDocumentsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) DeleteDocumentsReturns(result1 int64, result2 error) {
+	fake.deleteDocumentsMutex.Lock()
+	defer fake.deleteDocumentsMutex.Unlock()
+	fake.DeleteDocumentsStub = nil
+	fake.deleteDocumentsReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) DeleteDocumentsReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.deleteDocumentsMutex.Lock()
+	defer fake.deleteDocumentsMutex.Unlock()
+	fake.DeleteDocumentsStub = nil
+	if fake.deleteDocumentsReturnsOnCall == nil {
+		fake.deleteDocumentsReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.deleteDocumentsReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) DeleteExpiredDocuments(arg1 context.Context, arg2 time.Time) (int64, error) {
+	fake.deleteExpiredDocumentsMutex.Lock()
+	ret, specificReturn := fake.deleteExpiredDocumentsReturnsOnCall[len(fake.deleteExpiredDocumentsArgsForCall)]
+	fake.deleteExpiredDocumentsArgsForCall = append(fake.deleteExpiredDocumentsArgsForCall, struct {
+		arg1 context.Context
+		arg2 time.Time
+	}{arg1, arg2})
+	stub := fake.DeleteExpiredDocumentsStub
+	fakeReturns := fake.deleteExpiredDocumentsReturns
+	fake.recordInvocation("DeleteExpiredDocuments", []interface{}{arg1, arg2})
+	fake.deleteExpiredDocumentsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) DeleteExpiredDocumentsCallCount() int {
+	fake.deleteExpiredDocumentsMutex.RLock()
+	defer fake.deleteExpiredDocumentsMutex.RUnlock()
+	return len(fake.deleteExpiredDocumentsArgsForCall)
+}
+
+func (fake *FakeDatabase) DeleteExpiredDocumentsCalls(stub func(context.Context, time.Time) (int64, error)) {
+	fake.deleteExpiredDocumentsMutex.Lock()
+	defer fake.deleteExpiredDocumentsMutex.Unlock()
+	fake.DeleteExpiredDocumentsStub = stub
+}
+
+func (fake *FakeDatabase) DeleteExpiredDocumentsArgsForCall(i int) (context.Context, time.Time) {
+	fake.deleteExpiredDocumentsMutex.RLock()
+	defer fake.deleteExpiredDocumentsMutex.RUnlock()
+	argsForCall := fake.deleteExpiredDocumentsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) DeleteExpiredDocumentsReturns(result1 int64, result2 error) {
+	fake.deleteExpiredDocumentsMutex.Lock()
+	defer fake.deleteExpiredDocumentsMutex.Unlock()
+	fake.DeleteExpiredDocumentsStub = nil
+	fake.deleteExpiredDocumentsReturns = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) DeleteExpiredDocumentsReturnsOnCall(i int, result1 int64, result2 error) {
+	fake.deleteExpiredDocumentsMutex.Lock()
+	defer fake.deleteExpiredDocumentsMutex.Unlock()
+	fake.DeleteExpiredDocumentsStub = nil
+	if fake.deleteExpiredDocumentsReturnsOnCall == nil {
+		fake.deleteExpiredDocumentsReturnsOnCall = make(map[int]struct {
+			result1 int64
+			result2 error
+		})
+	}
+	fake.deleteExpiredDocumentsReturnsOnCall[i] = struct {
+		result1 int64
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetAndDeleteDocument(arg1 context.Context, arg2 string) (database.Document, error) {
+	fake.getAndDeleteDocumentMutex.Lock()
+	ret, specificReturn := fake.getAndDeleteDocumentReturnsOnCall[len(fake.getAndDeleteDocumentArgsForCall)]
+	fake.getAndDeleteDocumentArgsForCall = append(fake.getAndDeleteDocumentArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetAndDeleteDocumentStub
+	fakeReturns := fake.getAndDeleteDocumentReturns
+	fake.recordInvocation("GetAndDeleteDocument", []interface{}{arg1, arg2})
+	fake.getAndDeleteDocumentMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) GetAndDeleteDocumentCallCount() int {
+	fake.getAndDeleteDocumentMutex.RLock()
+	defer fake.getAndDeleteDocumentMutex.RUnlock()
+	return len(fake.getAndDeleteDocumentArgsForCall)
+}
+
+func (fake *FakeDatabase) GetAndDeleteDocumentCalls(stub func(context.Context, string) (database.Document, error)) {
+	fake.getAndDeleteDocumentMutex.Lock()
+	defer fake.getAndDeleteDocumentMutex.Unlock()
+	fake.GetAndDeleteDocumentStub = stub
+}
+
+func (fake *FakeDatabase) GetAndDeleteDocumentArgsForCall(i int) (context.Context, string) {
+	fake.getAndDeleteDocumentMutex.RLock()
+	defer fake.getAndDeleteDocumentMutex.RUnlock()
+	argsForCall := fake.getAndDeleteDocumentArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) GetAndDeleteDocumentReturns(result1 database.Document, result2 error) {
+	fake.getAndDeleteDocumentMutex.Lock()
+	defer fake.getAndDeleteDocumentMutex.Unlock()
+	fake.GetAndDeleteDocumentStub = nil
+	fake.getAndDeleteDocumentReturns = struct {
+		result1 database.Document
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetAndDeleteDocumentReturnsOnCall(i int, result1 database.Document, result2 error) {
+	fake.getAndDeleteDocumentMutex.Lock()
+	defer fake.getAndDeleteDocumentMutex.Unlock()
+	fake.GetAndDeleteDocumentStub = nil
+	if fake.getAndDeleteDocumentReturnsOnCall == nil {
+		fake.getAndDeleteDocumentReturnsOnCall = make(map[int]struct {
+			result1 database.Document
+			result2 error
+		})
+	}
+	fake.getAndDeleteDocumentReturnsOnCall[i] = struct {
+		result1 database.Document
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetContent(arg1 context.Context, arg2 string) (database.Content, error) {
+	fake.getContentMutex.Lock()
+	ret, specificReturn := fake.getContentReturnsOnCall[len(fake.getContentArgsForCall)]
+	fake.getContentArgsForCall = append(fake.getContentArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetContentStub
+	fakeReturns := fake.getContentReturns
+	fake.recordInvocation("GetContent", []interface{}{arg1, arg2})
+	fake.getContentMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) GetContentCallCount() int {
+	fake.getContentMutex.RLock()
+	defer fake.getContentMutex.RUnlock()
+	return len(fake.getContentArgsForCall)
+}
+
+func (fake *FakeDatabase) GetContentCalls(stub func(context.Context, string) (database.Content, error)) {
+	fake.getContentMutex.Lock()
+	defer fake.getContentMutex.Unlock()
+	fake.GetContentStub = stub
+}
+
+func (fake *FakeDatabase) GetContentArgsForCall(i int) (context.Context, string) {
+	fake.getContentMutex.RLock()
+	defer fake.getContentMutex.RUnlock()
+	argsForCall := fake.getContentArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) GetContentReturns(result1 database.Content, result2 error) {
+	fake.getContentMutex.Lock()
+	defer fake.getContentMutex.Unlock()
+	fake.GetContentStub = nil
+	fake.getContentReturns = struct {
+		result1 database.Content
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetContentReturnsOnCall(i int, result1 database.Content, result2 error) {
+	fake.getContentMutex.Lock()
+	defer fake.getContentMutex.Unlock()
+	fake.GetContentStub = nil
+	if fake.getContentReturnsOnCall == nil {
+		fake.getContentReturnsOnCall = make(map[int]struct {
+			result1 database.Content
+			result2 error
+		})
+	}
+	fake.getContentReturnsOnCall[i] = struct {
+		result1 database.Content
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeDatabase) GetDocument(arg1 context.Context, arg2 string) (database.Document, error) {
 	fake.getDocumentMutex.Lock()
 	ret, specificReturn := fake.getDocumentReturnsOnCall[len(fake.getDocumentArgsForCall)]
@@ -242,6 +818,271 @@ func (fake *FakeDatabase) GetDocumentReturnsOnCall(i int, result1 database.Docum
 	}{result1, result2}
 }
 
+func (fake *FakeDatabase) GetRevision(arg1 context.Context, arg2 string, arg3 int) (database.Revision, error) {
+	fake.getRevisionMutex.Lock()
+	ret, specificReturn := fake.getRevisionReturnsOnCall[len(fake.getRevisionArgsForCall)]
+	fake.getRevisionArgsForCall = append(fake.getRevisionArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+		arg3 int
+	}{arg1, arg2, arg3})
+	stub := fake.GetRevisionStub
+	fakeReturns := fake.getRevisionReturns
+	fake.recordInvocation("GetRevision", []interface{}{arg1, arg2, arg3})
+	fake.getRevisionMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) GetRevisionCallCount() int {
+	fake.getRevisionMutex.RLock()
+	defer fake.getRevisionMutex.RUnlock()
+	return len(fake.getRevisionArgsForCall)
+}
+
+func (fake *FakeDatabase) GetRevisionCalls(stub func(context.Context, string, int) (database.Revision, error)) {
+	fake.getRevisionMutex.Lock()
+	defer fake.getRevisionMutex.Unlock()
+	fake.GetRevisionStub = stub
+}
+
+func (fake *FakeDatabase) GetRevisionArgsForCall(i int) (context.Context, string, int) {
+	fake.getRevisionMutex.RLock()
+	defer fake.getRevisionMutex.RUnlock()
+	argsForCall := fake.getRevisionArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3
+}
+
+func (fake *FakeDatabase) GetRevisionReturns(result1 database.Revision, result2 error) {
+	fake.getRevisionMutex.Lock()
+	defer fake.getRevisionMutex.Unlock()
+	fake.GetRevisionStub = nil
+	fake.getRevisionReturns = struct {
+		result1 database.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetRevisionReturnsOnCall(i int, result1 database.Revision, result2 error) {
+	fake.getRevisionMutex.Lock()
+	defer fake.getRevisionMutex.Unlock()
+	fake.GetRevisionStub = nil
+	if fake.getRevisionReturnsOnCall == nil {
+		fake.getRevisionReturnsOnCall = make(map[int]struct {
+			result1 database.Revision
+			result2 error
+		})
+	}
+	fake.getRevisionReturnsOnCall[i] = struct {
+		result1 database.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetRevisions(arg1 context.Context, arg2 string) ([]database.Revision, error) {
+	fake.getRevisionsMutex.Lock()
+	ret, specificReturn := fake.getRevisionsReturnsOnCall[len(fake.getRevisionsArgsForCall)]
+	fake.getRevisionsArgsForCall = append(fake.getRevisionsArgsForCall, struct {
+		arg1 context.Context
+		arg2 string
+	}{arg1, arg2})
+	stub := fake.GetRevisionsStub
+	fakeReturns := fake.getRevisionsReturns
+	fake.recordInvocation("GetRevisions", []interface{}{arg1, arg2})
+	fake.getRevisionsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) GetRevisionsCallCount() int {
+	fake.getRevisionsMutex.RLock()
+	defer fake.getRevisionsMutex.RUnlock()
+	return len(fake.getRevisionsArgsForCall)
+}
+
+func (fake *FakeDatabase) GetRevisionsCalls(stub func(context.Context, string) ([]database.Revision, error)) {
+	fake.getRevisionsMutex.Lock()
+	defer fake.getRevisionsMutex.Unlock()
+	fake.GetRevisionsStub = stub
+}
+
+func (fake *FakeDatabase) GetRevisionsArgsForCall(i int) (context.Context, string) {
+	fake.getRevisionsMutex.RLock()
+	defer fake.getRevisionsMutex.RUnlock()
+	argsForCall := fake.getRevisionsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) GetRevisionsReturns(result1 []database.Revision, result2 error) {
+	fake.getRevisionsMutex.Lock()
+	defer fake.getRevisionsMutex.Unlock()
+	fake.GetRevisionsStub = nil
+	fake.getRevisionsReturns = struct {
+		result1 []database.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) GetRevisionsReturnsOnCall(i int, result1 []database.Revision, result2 error) {
+	fake.getRevisionsMutex.Lock()
+	defer fake.getRevisionsMutex.Unlock()
+	fake.GetRevisionsStub = nil
+	if fake.getRevisionsReturnsOnCall == nil {
+		fake.getRevisionsReturnsOnCall = make(map[int]struct {
+			result1 []database.Revision
+			result2 error
+		})
+	}
+	fake.getRevisionsReturnsOnCall[i] = struct {
+		result1 []database.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) ImportDocument(arg1 context.Context, arg2 database.Document, arg3 []database.Revision, arg4 bool) error {
+	var arg3Copy []database.Revision
+	if arg3 != nil {
+		arg3Copy = make([]database.Revision, len(arg3))
+		copy(arg3Copy, arg3)
+	}
+	fake.importDocumentMutex.Lock()
+	ret, specificReturn := fake.importDocumentReturnsOnCall[len(fake.importDocumentArgsForCall)]
+	fake.importDocumentArgsForCall = append(fake.importDocumentArgsForCall, struct {
+		arg1 context.Context
+		arg2 database.Document
+		arg3 []database.Revision
+		arg4 bool
+	}{arg1, arg2, arg3Copy, arg4})
+	stub := fake.ImportDocumentStub
+	fakeReturns := fake.importDocumentReturns
+	fake.recordInvocation("ImportDocument", []interface{}{arg1, arg2, arg3Copy, arg4})
+	fake.importDocumentMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2, arg3, arg4)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDatabase) ImportDocumentCallCount() int {
+	fake.importDocumentMutex.RLock()
+	defer fake.importDocumentMutex.RUnlock()
+	return len(fake.importDocumentArgsForCall)
+}
+
+func (fake *FakeDatabase) ImportDocumentCalls(stub func(context.Context, database.Document, []database.Revision, bool) error) {
+	fake.importDocumentMutex.Lock()
+	defer fake.importDocumentMutex.Unlock()
+	fake.ImportDocumentStub = stub
+}
+
+func (fake *FakeDatabase) ImportDocumentArgsForCall(i int) (context.Context, database.Document, []database.Revision, bool) {
+	fake.importDocumentMutex.RLock()
+	defer fake.importDocumentMutex.RUnlock()
+	argsForCall := fake.importDocumentArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2, argsForCall.arg3, argsForCall.arg4
+}
+
+func (fake *FakeDatabase) ImportDocumentReturns(result1 error) {
+	fake.importDocumentMutex.Lock()
+	defer fake.importDocumentMutex.Unlock()
+	fake.ImportDocumentStub = nil
+	fake.importDocumentReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) ImportDocumentReturnsOnCall(i int, result1 error) {
+	fake.importDocumentMutex.Lock()
+	defer fake.importDocumentMutex.Unlock()
+	fake.ImportDocumentStub = nil
+	if fake.importDocumentReturnsOnCall == nil {
+		fake.importDocumentReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.importDocumentReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) ListDocuments(arg1 context.Context, arg2 database.DocumentFilter) ([]database.Document, error) {
+	fake.listDocumentsMutex.Lock()
+	ret, specificReturn := fake.listDocumentsReturnsOnCall[len(fake.listDocumentsArgsForCall)]
+	fake.listDocumentsArgsForCall = append(fake.listDocumentsArgsForCall, struct {
+		arg1 context.Context
+		arg2 database.DocumentFilter
+	}{arg1, arg2})
+	stub := fake.ListDocumentsStub
+	fakeReturns := fake.listDocumentsReturns
+	fake.recordInvocation("ListDocuments", []interface{}{arg1, arg2})
+	fake.listDocumentsMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) ListDocumentsCallCount() int {
+	fake.listDocumentsMutex.RLock()
+	defer fake.listDocumentsMutex.RUnlock()
+	return len(fake.listDocumentsArgsForCall)
+}
+
+func (fake *FakeDatabase) ListDocumentsCalls(stub func(context.Context, database.DocumentFilter) ([]database.Document, error)) {
+	fake.listDocumentsMutex.Lock()
+	defer fake.listDocumentsMutex.Unlock()
+	fake.ListDocumentsStub = stub
+}
+
+func (fake *FakeDatabase) ListDocumentsArgsForCall(i int) (context.Context, database.DocumentFilter) {
+	fake.listDocumentsMutex.RLock()
+	defer fake.listDocumentsMutex.RUnlock()
+	argsForCall := fake.listDocumentsArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) ListDocumentsReturns(result1 []database.Document, result2 error) {
+	fake.listDocumentsMutex.Lock()
+	defer fake.listDocumentsMutex.Unlock()
+	fake.ListDocumentsStub = nil
+	fake.listDocumentsReturns = struct {
+		result1 []database.Document
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) ListDocumentsReturnsOnCall(i int, result1 []database.Document, result2 error) {
+	fake.listDocumentsMutex.Lock()
+	defer fake.listDocumentsMutex.Unlock()
+	fake.ListDocumentsStub = nil
+	if fake.listDocumentsReturnsOnCall == nil {
+		fake.listDocumentsReturnsOnCall = make(map[int]struct {
+			result1 []database.Document
+			result2 error
+		})
+	}
+	fake.listDocumentsReturnsOnCall[i] = struct {
+		result1 []database.Document
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeDatabase) Migrate(arg1 context.Context) error {
 	fake.migrateMutex.Lock()
 	ret, specificReturn := fake.migrateReturnsOnCall[len(fake.migrateArgsForCall)]
@@ -303,17 +1144,298 @@ func (fake *FakeDatabase) MigrateReturnsOnCall(i int, result1 error) {
 	}{result1}
 }
 
+func (fake *FakeDatabase) MigrateDown(arg1 context.Context, arg2 int) error {
+	fake.migrateDownMutex.Lock()
+	ret, specificReturn := fake.migrateDownReturnsOnCall[len(fake.migrateDownArgsForCall)]
+	fake.migrateDownArgsForCall = append(fake.migrateDownArgsForCall, struct {
+		arg1 context.Context
+		arg2 int
+	}{arg1, arg2})
+	stub := fake.MigrateDownStub
+	fakeReturns := fake.migrateDownReturns
+	fake.recordInvocation("MigrateDown", []interface{}{arg1, arg2})
+	fake.migrateDownMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDatabase) MigrateDownCallCount() int {
+	fake.migrateDownMutex.RLock()
+	defer fake.migrateDownMutex.RUnlock()
+	return len(fake.migrateDownArgsForCall)
+}
+
+func (fake *FakeDatabase) MigrateDownCalls(stub func(context.Context, int) error) {
+	fake.migrateDownMutex.Lock()
+	defer fake.migrateDownMutex.Unlock()
+	fake.MigrateDownStub = stub
+}
+
+func (fake *FakeDatabase) MigrateDownArgsForCall(i int) (context.Context, int) {
+	fake.migrateDownMutex.RLock()
+	defer fake.migrateDownMutex.RUnlock()
+	argsForCall := fake.migrateDownArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) MigrateDownReturns(result1 error) {
+	fake.migrateDownMutex.Lock()
+	defer fake.migrateDownMutex.Unlock()
+	fake.MigrateDownStub = nil
+	fake.migrateDownReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) MigrateDownReturnsOnCall(i int, result1 error) {
+	fake.migrateDownMutex.Lock()
+	defer fake.migrateDownMutex.Unlock()
+	fake.MigrateDownStub = nil
+	if fake.migrateDownReturnsOnCall == nil {
+		fake.migrateDownReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.migrateDownReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) MigrationStatus(arg1 context.Context) ([]database.MigrationStatus, error) {
+	fake.migrationStatusMutex.Lock()
+	ret, specificReturn := fake.migrationStatusReturnsOnCall[len(fake.migrationStatusArgsForCall)]
+	fake.migrationStatusArgsForCall = append(fake.migrationStatusArgsForCall, struct {
+		arg1 context.Context
+	}{arg1})
+	stub := fake.MigrationStatusStub
+	fakeReturns := fake.migrationStatusReturns
+	fake.recordInvocation("MigrationStatus", []interface{}{arg1})
+	fake.migrationStatusMutex.Unlock()
+	if stub != nil {
+		return stub(arg1)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) MigrationStatusCallCount() int {
+	fake.migrationStatusMutex.RLock()
+	defer fake.migrationStatusMutex.RUnlock()
+	return len(fake.migrationStatusArgsForCall)
+}
+
+func (fake *FakeDatabase) MigrationStatusCalls(stub func(context.Context) ([]database.MigrationStatus, error)) {
+	fake.migrationStatusMutex.Lock()
+	defer fake.migrationStatusMutex.Unlock()
+	fake.MigrationStatusStub = stub
+}
+
+func (fake *FakeDatabase) MigrationStatusArgsForCall(i int) context.Context {
+	fake.migrationStatusMutex.RLock()
+	defer fake.migrationStatusMutex.RUnlock()
+	argsForCall := fake.migrationStatusArgsForCall[i]
+	return argsForCall.arg1
+}
+
+func (fake *FakeDatabase) MigrationStatusReturns(result1 []database.MigrationStatus, result2 error) {
+	fake.migrationStatusMutex.Lock()
+	defer fake.migrationStatusMutex.Unlock()
+	fake.MigrationStatusStub = nil
+	fake.migrationStatusReturns = struct {
+		result1 []database.MigrationStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) MigrationStatusReturnsOnCall(i int, result1 []database.MigrationStatus, result2 error) {
+	fake.migrationStatusMutex.Lock()
+	defer fake.migrationStatusMutex.Unlock()
+	fake.MigrationStatusStub = nil
+	if fake.migrationStatusReturnsOnCall == nil {
+		fake.migrationStatusReturnsOnCall = make(map[int]struct {
+			result1 []database.MigrationStatus
+			result2 error
+		})
+	}
+	fake.migrationStatusReturnsOnCall[i] = struct {
+		result1 []database.MigrationStatus
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) UpdateDocument(arg1 context.Context, arg2 database.Document) (database.Revision, error) {
+	fake.updateDocumentMutex.Lock()
+	ret, specificReturn := fake.updateDocumentReturnsOnCall[len(fake.updateDocumentArgsForCall)]
+	fake.updateDocumentArgsForCall = append(fake.updateDocumentArgsForCall, struct {
+		arg1 context.Context
+		arg2 database.Document
+	}{arg1, arg2})
+	stub := fake.UpdateDocumentStub
+	fakeReturns := fake.updateDocumentReturns
+	fake.recordInvocation("UpdateDocument", []interface{}{arg1, arg2})
+	fake.updateDocumentMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fakeReturns.result1, fakeReturns.result2
+}
+
+func (fake *FakeDatabase) UpdateDocumentCallCount() int {
+	fake.updateDocumentMutex.RLock()
+	defer fake.updateDocumentMutex.RUnlock()
+	return len(fake.updateDocumentArgsForCall)
+}
+
+func (fake *FakeDatabase) UpdateDocumentCalls(stub func(context.Context, database.Document) (database.Revision, error)) {
+	fake.updateDocumentMutex.Lock()
+	defer fake.updateDocumentMutex.Unlock()
+	fake.UpdateDocumentStub = stub
+}
+
+func (fake *FakeDatabase) UpdateDocumentArgsForCall(i int) (context.Context, database.Document) {
+	fake.updateDocumentMutex.RLock()
+	defer fake.updateDocumentMutex.RUnlock()
+	argsForCall := fake.updateDocumentArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) UpdateDocumentReturns(result1 database.Revision, result2 error) {
+	fake.updateDocumentMutex.Lock()
+	defer fake.updateDocumentMutex.Unlock()
+	fake.UpdateDocumentStub = nil
+	fake.updateDocumentReturns = struct {
+		result1 database.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) UpdateDocumentReturnsOnCall(i int, result1 database.Revision, result2 error) {
+	fake.updateDocumentMutex.Lock()
+	defer fake.updateDocumentMutex.Unlock()
+	fake.UpdateDocumentStub = nil
+	if fake.updateDocumentReturnsOnCall == nil {
+		fake.updateDocumentReturnsOnCall = make(map[int]struct {
+			result1 database.Revision
+			result2 error
+		})
+	}
+	fake.updateDocumentReturnsOnCall[i] = struct {
+		result1 database.Revision
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeDatabase) UpsertDocument(arg1 context.Context, arg2 database.Document) error {
+	fake.upsertDocumentMutex.Lock()
+	ret, specificReturn := fake.upsertDocumentReturnsOnCall[len(fake.upsertDocumentArgsForCall)]
+	fake.upsertDocumentArgsForCall = append(fake.upsertDocumentArgsForCall, struct {
+		arg1 context.Context
+		arg2 database.Document
+	}{arg1, arg2})
+	stub := fake.UpsertDocumentStub
+	fakeReturns := fake.upsertDocumentReturns
+	fake.recordInvocation("UpsertDocument", []interface{}{arg1, arg2})
+	fake.upsertDocumentMutex.Unlock()
+	if stub != nil {
+		return stub(arg1, arg2)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fakeReturns.result1
+}
+
+func (fake *FakeDatabase) UpsertDocumentCallCount() int {
+	fake.upsertDocumentMutex.RLock()
+	defer fake.upsertDocumentMutex.RUnlock()
+	return len(fake.upsertDocumentArgsForCall)
+}
+
+func (fake *FakeDatabase) UpsertDocumentCalls(stub func(context.Context, database.Document) error) {
+	fake.upsertDocumentMutex.Lock()
+	defer fake.upsertDocumentMutex.Unlock()
+	fake.UpsertDocumentStub = stub
+}
+
+func (fake *FakeDatabase) UpsertDocumentArgsForCall(i int) (context.Context, database.Document) {
+	fake.upsertDocumentMutex.RLock()
+	defer fake.upsertDocumentMutex.RUnlock()
+	argsForCall := fake.upsertDocumentArgsForCall[i]
+	return argsForCall.arg1, argsForCall.arg2
+}
+
+func (fake *FakeDatabase) UpsertDocumentReturns(result1 error) {
+	fake.upsertDocumentMutex.Lock()
+	defer fake.upsertDocumentMutex.Unlock()
+	fake.UpsertDocumentStub = nil
+	fake.upsertDocumentReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeDatabase) UpsertDocumentReturnsOnCall(i int, result1 error) {
+	fake.upsertDocumentMutex.Lock()
+	defer fake.upsertDocumentMutex.Unlock()
+	fake.UpsertDocumentStub = nil
+	if fake.upsertDocumentReturnsOnCall == nil {
+		fake.upsertDocumentReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.upsertDocumentReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeDatabase) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
 	fake.closeMutex.RLock()
 	defer fake.closeMutex.RUnlock()
+	fake.countDocumentsMutex.RLock()
+	defer fake.countDocumentsMutex.RUnlock()
 	fake.createDocumentMutex.RLock()
 	defer fake.createDocumentMutex.RUnlock()
+	fake.deleteDocumentMutex.RLock()
+	defer fake.deleteDocumentMutex.RUnlock()
+	fake.deleteDocumentsMutex.RLock()
+	defer fake.deleteDocumentsMutex.RUnlock()
+	fake.deleteExpiredDocumentsMutex.RLock()
+	defer fake.deleteExpiredDocumentsMutex.RUnlock()
+	fake.getAndDeleteDocumentMutex.RLock()
+	defer fake.getAndDeleteDocumentMutex.RUnlock()
+	fake.getContentMutex.RLock()
+	defer fake.getContentMutex.RUnlock()
 	fake.getDocumentMutex.RLock()
 	defer fake.getDocumentMutex.RUnlock()
+	fake.getRevisionMutex.RLock()
+	defer fake.getRevisionMutex.RUnlock()
+	fake.getRevisionsMutex.RLock()
+	defer fake.getRevisionsMutex.RUnlock()
+	fake.importDocumentMutex.RLock()
+	defer fake.importDocumentMutex.RUnlock()
+	fake.listDocumentsMutex.RLock()
+	defer fake.listDocumentsMutex.RUnlock()
 	fake.migrateMutex.RLock()
 	defer fake.migrateMutex.RUnlock()
+	fake.migrateDownMutex.RLock()
+	defer fake.migrateDownMutex.RUnlock()
+	fake.migrationStatusMutex.RLock()
+	defer fake.migrationStatusMutex.RUnlock()
+	fake.updateDocumentMutex.RLock()
+	defer fake.updateDocumentMutex.RUnlock()
+	fake.upsertDocumentMutex.RLock()
+	defer fake.upsertDocumentMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
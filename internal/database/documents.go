@@ -0,0 +1,146 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// DocumentSync loads custom documents from files on disk into the database,
+// and can optionally keep them up to date as the files change.
+type DocumentSync struct {
+	db    Database
+	files map[string]string // Document IDs to file paths
+
+	// Modification times and sizes of files at the last sync, used to detect changes
+	seen map[string]os.FileInfo
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewDocumentSync creates a DocumentSync for the given map of document IDs to
+// file paths. IDs without a path are managed by hand and are ignored.
+func NewDocumentSync(db Database, files map[string]string) *DocumentSync {
+	d := &DocumentSync{
+		db:    db,
+		files: make(map[string]string),
+		seen:  make(map[string]os.FileInfo),
+		stop:  make(chan struct{}),
+		done:  make(chan struct{}),
+	}
+
+	for id, path := range files {
+		if path != "" {
+			d.files[id] = path
+		}
+	}
+
+	return d
+}
+
+// Sync upserts every document whose file has changed since the last sync.
+func (d *DocumentSync) Sync(ctx context.Context) error {
+	var errs []error
+
+	for id, path := range d.files {
+		if err := d.syncFile(ctx, id, path); err != nil {
+			errs = append(errs, fmt.Errorf("document %s: %w", id, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func (d *DocumentSync) syncFile(ctx context.Context, id, path string) error {
+	info, err := os.Stat(path)
+
+	if err != nil {
+		return err
+	}
+
+	if prev, ok := d.seen[id]; ok && prev.ModTime().Equal(info.ModTime()) && prev.Size() == info.Size() {
+		return nil
+	}
+
+	content, err := os.ReadFile(path)
+
+	if err != nil {
+		return err
+	}
+
+	// Avoid bumping updated_at on every startup if nothing has changed
+	document, err := d.db.GetDocument(ctx, id)
+
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	if err != nil || document.Content != string(content) || document.ExpiresAt != nil || document.BurnAfterRead {
+		if err := d.db.UpsertDocument(ctx, Document{ID: id, Content: string(content)}); err != nil {
+			return err
+		}
+
+		log.Info().
+			Str("id", id).
+			Str("path", path).
+			Msg("Loaded custom document")
+	}
+
+	d.seen[id] = info
+	return nil
+}
+
+// Start re-syncs documents every interval in the background until Stop is called.
+func (d *DocumentSync) Start(interval time.Duration) {
+	go func() {
+		defer close(d.done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-d.stop:
+				return
+			case <-ticker.C:
+				ctx, cancel := context.WithTimeout(context.Background(), interval)
+
+				if err := d.Sync(ctx); err != nil {
+					log.Error().
+						Err(err).
+						Msg("Failed to sync custom documents")
+				}
+
+				cancel()
+			}
+		}
+	}()
+}
+
+// Stop signals the background sync to exit and waits for it to finish.
+func (d *DocumentSync) Stop() {
+	close(d.stop)
+	<-d.done
+}
@@ -0,0 +1,87 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentSync(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "about.md")
+	require.NoError(t, os.WriteFile(path, []byte("About this instance"), 0o644))
+
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{}, sql.ErrNoRows)
+
+	sync := database.NewDocumentSync(mockDB, map[string]string{
+		"about":  path,
+		"legacy": "", // Managed by hand, so never touched
+	})
+
+	// The first sync loads the file
+	require.NoError(t, sync.Sync(context.Background()))
+	require.Equal(t, 1, mockDB.UpsertDocumentCallCount())
+
+	_, document := mockDB.UpsertDocumentArgsForCall(0)
+	require.Equal(t, "about", document.ID)
+	require.Equal(t, "About this instance", document.Content)
+
+	// Nothing has changed, so there's nothing to do
+	require.NoError(t, sync.Sync(context.Background()))
+	require.Equal(t, 1, mockDB.UpsertDocumentCallCount())
+
+	// Changing the file loads it again
+	require.NoError(t, os.WriteFile(path, []byte("About this instance, updated"), 0o644))
+	require.NoError(t, os.Chtimes(path, time.Now(), time.Now().Add(time.Minute)))
+
+	require.NoError(t, sync.Sync(context.Background()))
+	require.Equal(t, 2, mockDB.UpsertDocumentCallCount())
+
+	_, document = mockDB.UpsertDocumentArgsForCall(1)
+	require.Equal(t, "About this instance, updated", document.Content)
+}
+
+func TestDocumentSyncUnchanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "about.md")
+	require.NoError(t, os.WriteFile(path, []byte("About this instance"), 0o644))
+
+	// The database already has the current version of the file
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "about", Content: "About this instance"}, nil)
+
+	sync := database.NewDocumentSync(mockDB, map[string]string{"about": path})
+
+	require.NoError(t, sync.Sync(context.Background()))
+	require.Equal(t, 0, mockDB.UpsertDocumentCallCount())
+}
+
+func TestDocumentSyncMissingFile(t *testing.T) {
+	sync := database.NewDocumentSync(&databasefakes.FakeDatabase{}, map[string]string{
+		"about": filepath.Join(t.TempDir(), "missing.md"),
+	})
+
+	require.Error(t, sync.Sync(context.Background()))
+}
@@ -0,0 +1,117 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+)
+
+// File is one of the files of a document with several. The document's own
+// Content and Language are those of its first file.
+type File struct {
+	Name        string `db:"name" json:"name"`
+	Language    string `db:"language" json:"language"`
+	ContentType string `db:"content_type" json:"content_type"` // See Document.ContentType
+	Content     string `db:"content" json:"content"`
+	ContentHash string `db:"content_hash" json:"content_hash"` // See Document.ContentHash
+	Size        int64  `db:"size" json:"size"`                 // See Document.Size
+}
+
+// querier is implemented by both *sql.DB and *sql.Tx.
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// addFiles saves a document's files, holding their content.
+func addFiles(ctx context.Context, tx *sql.Tx, d dialect, document Document) error {
+	for i, file := range document.Files {
+		hash, err := holdContent(ctx, tx, d, Document{Content: file.Content, ContentHash: file.ContentHash,
+			Size: file.Size})
+
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO document_files (document_id, position, name, language, "+
+			"content_type, content_hash) VALUES ("+d.placeholder(1)+", "+d.placeholder(2)+", "+d.placeholder(3)+", "+
+			d.placeholder(4)+", "+d.placeholder(5)+", "+d.placeholder(6)+")", document.ID, i, file.Name, file.Language,
+			file.ContentType, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// deleteFiles deletes the files of a document, releasing their content.
+func deleteFiles(ctx context.Context, tx *sql.Tx, d dialect, id string) error {
+	hashes, err := queryStrings(ctx, tx, "SELECT content_hash FROM document_files WHERE document_id = "+
+		d.placeholder(1), id)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM document_files WHERE document_id = "+d.placeholder(1),
+		id); err != nil {
+		return err
+	}
+
+	for _, hash := range hashes {
+		if err := releaseContent(ctx, tx, d, hash); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// getFiles reads a document's files, in the order they were added. It returns
+// nil if the document only has its own content.
+func getFiles(ctx context.Context, q querier, d dialect, id string) ([]File, error) {
+	rows, err := q.QueryContext(ctx, "SELECT f.name, f.language, f.content_type, COALESCE(c.content, ''), "+
+		"COALESCE(c.encoding, ''), f.content_hash, COALESCE(c.size, 0) "+
+		"FROM document_files f LEFT JOIN contents c ON c.hash = f.content_hash "+
+		"WHERE f.document_id = "+d.placeholder(1)+" ORDER BY f.position", id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var files []File
+
+	for rows.Next() {
+		var file File
+		var encoding string
+
+		if err := rows.Scan(&file.Name, &file.Language, &file.ContentType, &file.Content, &encoding,
+			&file.ContentHash, &file.Size); err != nil {
+			return nil, err
+		}
+
+		if file.Content, err = decodeContent(file.Content, encoding); err != nil {
+			return nil, err
+		}
+
+		files = append(files, file)
+	}
+
+	return files, rows.Err()
+}
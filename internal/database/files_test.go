@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentFiles(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	files := []File{
+		{Name: "config.yml", Language: "YAML", Content: "debug: true"},
+		{Name: "app.log", Language: "plaintext", Content: "started"},
+		{Name: "fix.patch", Language: "Diff", Content: "started"},
+	}
+
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: files[0].Content, Files: files}))
+
+	// Files are read back in order, and share content like anything else
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Len(t, document.Files, 3)
+
+	for i, file := range document.Files {
+		require.Equal(t, files[i].Name, file.Name)
+		require.Equal(t, files[i].Language, file.Language)
+		require.Equal(t, files[i].Content, file.Content)
+		require.Equal(t, HashContent(files[i].Content), file.ContentHash)
+	}
+
+	requireReferences(t, db, "debug: true", 3)
+	requireReferences(t, db, "started", 2)
+
+	// Documents with a single file don't list it
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "87654321", Content: "single"}))
+
+	document, err = db.GetDocument(ctx, "87654321")
+	require.NoError(t, err)
+	require.Nil(t, document.Files)
+
+	// Deleting a document releases its files
+	document, err = db.GetAndDeleteDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Len(t, document.Files, 3)
+
+	requireReferences(t, db, "debug: true", 0)
+	requireReferences(t, db, "started", 0)
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM document_files").Scan(&n))
+	require.Zero(t, n)
+
+	_, err = db.GetDocument(ctx, "12345678")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
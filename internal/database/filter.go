@@ -0,0 +1,183 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// DocumentFilter picks out the documents to list, count or delete. Fields left
+// as their zero value don't filter anything.
+type DocumentFilter struct {
+	Prefix        string    // Only documents whose IDs start with Prefix
+	CreatedBefore time.Time // Only documents created before this time
+	CreatedAfter  time.Time // Only documents created at or after this time
+	ExpiredBy     time.Time // Only documents that expire at or before this time
+	NeverExpires  bool      // Only documents without an expiry time
+	Except        []string  // Documents with these IDs are left out
+	MinSize       int64     // Only documents of at least this many bytes
+	MaxSize       int64     // Only documents of at most this many bytes
+
+	Limit int // Most documents to list, oldest first. Not used when counting or deleting.
+}
+
+// DocumentCount is how many documents match a filter, and their total size.
+type DocumentCount struct {
+	Documents int64 `json:"documents"`
+	Size      int64 `json:"size"` // in bytes
+}
+
+// likeEscaper escapes the wildcards in a LIKE pattern, with ! as the escape
+// character since not every database treats backslashes the same way.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// where builds the WHERE clause selecting a filter's documents from
+// documentsTable, and its arguments.
+func (f DocumentFilter) where(d dialect) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, d.placeholder(len(args))))
+	}
+
+	if f.Prefix != "" {
+		add("d.id LIKE %s ESCAPE '!'", likeEscaper.Replace(f.Prefix)+"%")
+	}
+
+	// SQLite stores timestamps as text, so they have to be compared in the same zone
+	if !f.CreatedBefore.IsZero() {
+		add("d.created_at < %s", f.CreatedBefore.UTC())
+	}
+
+	if !f.CreatedAfter.IsZero() {
+		add("d.created_at >= %s", f.CreatedAfter.UTC())
+	}
+
+	if !f.ExpiredBy.IsZero() {
+		add("d.expires_at <= %s", f.ExpiredBy.UTC())
+	}
+
+	if f.NeverExpires {
+		conditions = append(conditions, "d.expires_at IS NULL")
+	}
+
+	for _, id := range f.Except {
+		add("d.id <> %s", id)
+	}
+
+	if f.MinSize > 0 {
+		add(documentSize+" >= %s", f.MinSize)
+	}
+
+	if f.MaxSize > 0 {
+		add(documentSize+" <= %s", f.MaxSize)
+	}
+
+	if len(conditions) == 0 {
+		return "", nil
+	}
+
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+// documentInfoColumns are documentColumns without content, which isn't needed
+// to list documents. Documents saved before content was shared still have
+// theirs read, as their hash is worked out from it.
+var documentInfoColumns = strings.Replace(documentColumns, "COALESCE(c.content, d.content)",
+	"CASE WHEN d.content_hash = '' THEN d.content ELSE '' END", 1)
+
+// listDocuments lists the documents matching a filter, oldest first, without
+// their content or files.
+func listDocuments(ctx context.Context, db *sql.DB, d dialect, filter DocumentFilter) ([]Document, error) {
+	where, args := filter.where(d)
+	query := "SELECT " + documentInfoColumns + " FROM " + documentsTable + where + " ORDER BY d.created_at, d.id"
+
+	if filter.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+
+	rows, err := db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	documents := []Document{}
+
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+
+		if err != nil {
+			return nil, err
+		}
+
+		doc.Content = ""
+		documents = append(documents, doc)
+	}
+
+	return documents, rows.Err()
+}
+
+// countDocuments counts the documents matching a filter, and adds up their size.
+func countDocuments(ctx context.Context, db *sql.DB, d dialect, filter DocumentFilter) (DocumentCount, error) {
+	where, args := filter.where(d)
+
+	var count DocumentCount
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*), COALESCE(SUM(size), 0) FROM (SELECT "+documentSize+
+		" AS size FROM "+documentsTable+where+") matched", args...).Scan(&count.Documents, &count.Size)
+
+	return count, err
+}
+
+// deleteDocuments deletes every document matching a filter, returning the
+// number deleted.
+func deleteDocuments(ctx context.Context, tx *sql.Tx, d dialect, filter DocumentFilter) (int64, error) {
+	where, args := filter.where(d)
+	ids, err := queryStrings(ctx, tx, "SELECT d.id FROM "+documentsTable+where, args...)
+
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+
+	for _, id := range ids {
+		err := deleteDocument(ctx, tx, d, id)
+
+		// It may have been deleted since it was selected
+		if errors.Is(err, sql.ErrNoRows) {
+			continue
+		}
+
+		if err != nil {
+			return 0, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
@@ -0,0 +1,102 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDocumentFilter(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	now := time.Now().UTC().Truncate(time.Second)
+	expired := now.Add(-time.Minute)
+
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "abc00001", Content: "short"}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "abc00002", Content: "a much longer document"}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "ab_00003", Content: "short", ExpiresAt: &expired}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "xyz00004", Content: "first file", Files: []File{
+		{Name: "a.txt", Content: "first file"},
+		{Name: "b.txt", Content: "second file"},
+	}}))
+
+	// Backdate the first two documents
+	_, err := db.Exec("UPDATE documents SET created_at = $1 WHERE id IN ('abc00001', 'abc00002')",
+		now.Add(-48*time.Hour))
+	require.NoError(t, err)
+
+	ids := func(filter DocumentFilter) []string {
+		documents, err := db.ListDocuments(ctx, filter)
+		require.NoError(t, err)
+
+		ids := []string{}
+
+		for _, document := range documents {
+			require.Empty(t, document.Content)
+			ids = append(ids, document.ID)
+		}
+
+		return ids
+	}
+
+	require.Equal(t, []string{"abc00001", "abc00002", "ab_00003", "xyz00004"}, ids(DocumentFilter{}))
+	require.Equal(t, []string{"abc00001", "abc00002"}, ids(DocumentFilter{Prefix: "abc"}))
+	require.Equal(t, []string{"ab_00003"}, ids(DocumentFilter{Prefix: "ab_"})) // Not a wildcard
+	require.Equal(t, []string{"abc00001", "abc00002"}, ids(DocumentFilter{CreatedBefore: now.Add(-time.Hour)}))
+	require.Equal(t, []string{"ab_00003", "xyz00004"}, ids(DocumentFilter{CreatedAfter: now.Add(-time.Hour)}))
+	require.Equal(t, []string{"ab_00003"}, ids(DocumentFilter{ExpiredBy: now}))
+	require.Equal(t, []string{"abc00002", "xyz00004"}, ids(DocumentFilter{MinSize: 20}))
+	require.Equal(t, []string{"abc00001", "ab_00003"}, ids(DocumentFilter{MaxSize: 5}))
+	require.Equal(t, []string{"abc00001"}, ids(DocumentFilter{Limit: 1}))
+	require.Equal(t, []string{"abc00001", "abc00002", "xyz00004"}, ids(DocumentFilter{NeverExpires: true}))
+	require.Equal(t, []string{"abc00002"}, ids(DocumentFilter{NeverExpires: true,
+		CreatedBefore: now.Add(-time.Hour), Except: []string{"abc00001"}}))
+
+	// Documents with several files are as large as all of them
+	documents, err := db.ListDocuments(ctx, DocumentFilter{Prefix: "xyz"})
+	require.NoError(t, err)
+	require.Equal(t, int64(len("first file")+len("second file")), documents[0].Size)
+	require.Equal(t, HashContent("first file"), documents[0].ContentHash)
+
+	count, err := db.CountDocuments(ctx, DocumentFilter{})
+	require.NoError(t, err)
+	require.Equal(t, DocumentCount{Documents: 4, Size: 5 + 22 + 5 + 21}, count)
+
+	count, err = db.CountDocuments(ctx, DocumentFilter{Prefix: "nothing"})
+	require.NoError(t, err)
+	require.Equal(t, DocumentCount{}, count)
+
+	// Deleting documents releases their content
+	n, err := db.DeleteDocuments(ctx, DocumentFilter{CreatedBefore: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+
+	_, err = db.GetDocument(ctx, "abc00002")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	requireReferences(t, db, "short", 2)
+	requireReferences(t, db, "a much longer document", 0)
+
+	require.Equal(t, []string{"ab_00003", "xyz00004"}, ids(DocumentFilter{}))
+}
@@ -0,0 +1,111 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// ErrDocumentExists is returned by ImportDocument when a document with the same
+// ID already exists, and isn't to be overwritten.
+var ErrDocumentExists = errors.New("document already exists")
+
+// importDocument saves a document exactly as it was exported from another
+// database, timestamps and tokens included, along with its revisions and
+// files. A document without revisions gets its content as its first one.
+func importDocument(ctx context.Context, tx *sql.Tx, d dialect, document Document, revisions []Revision,
+	overwrite bool) error {
+	if overwrite {
+		if err := deleteDocument(ctx, tx, d, document.ID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	} else {
+		var id string
+		err := tx.QueryRowContext(ctx, "SELECT id FROM documents WHERE id = "+d.placeholder(1)+d.forUpdate,
+			document.ID).Scan(&id)
+
+		if err == nil {
+			return ErrDocumentExists
+		}
+
+		if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+	}
+
+	now := time.Now()
+
+	if document.CreatedAt.IsZero() {
+		document.CreatedAt = now
+	}
+
+	if document.UpdatedAt.IsZero() {
+		document.UpdatedAt = document.CreatedAt
+	}
+
+	var expiresAt *time.Time
+
+	if document.ExpiresAt != nil {
+		t := document.ExpiresAt.UTC()
+		expiresAt = &t
+	}
+
+	hash, err := holdContent(ctx, tx, d, document)
+
+	if err != nil {
+		return err
+	}
+
+	// SQLite stores timestamps as text, so they're saved in the same zone as the ones it generates
+	if _, err := tx.ExecContext(ctx, "INSERT INTO documents (id, content, created_at, updated_at, expires_at, "+
+		"burn_after_read, delete_token_hash, edit_token_hash, content_hash, language, content_type) VALUES ("+
+		d.placeholder(1)+", '', "+d.placeholder(2)+", "+d.placeholder(3)+", "+d.placeholder(4)+", "+
+		d.placeholder(5)+", "+d.placeholder(6)+", "+d.placeholder(7)+", "+d.placeholder(8)+", "+d.placeholder(9)+
+		", "+d.placeholder(10)+")", document.ID, document.CreatedAt.UTC(), document.UpdatedAt.UTC(), expiresAt,
+		document.BurnAfterRead, document.DeleteTokenHash, document.EditTokenHash, hash, document.Language,
+		document.ContentType); err != nil {
+		return err
+	}
+
+	if len(revisions) == 0 {
+		revisions = []Revision{{Revision: 1, Content: document.Content, ContentHash: document.ContentHash,
+			CreatedAt: document.CreatedAt}}
+	}
+
+	for _, rev := range revisions {
+		hash, err := holdContent(ctx, tx, d, Document{Content: rev.Content, ContentHash: rev.ContentHash})
+
+		if err != nil {
+			return err
+		}
+
+		if rev.CreatedAt.IsZero() {
+			rev.CreatedAt = document.CreatedAt
+		}
+
+		if _, err := tx.ExecContext(ctx, "INSERT INTO document_revisions (document_id, revision, content, "+
+			"content_hash, created_at) VALUES ("+d.placeholder(1)+", "+d.placeholder(2)+", '', "+d.placeholder(3)+
+			", "+d.placeholder(4)+")", document.ID, rev.Revision, hash, rev.CreatedAt.UTC()); err != nil {
+			return err
+		}
+	}
+
+	return addFiles(ctx, tx, d, document)
+}
@@ -0,0 +1,97 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportDocument(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+	require.NoError(t, db.Migrate(ctx))
+
+	created := time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	expires := created.Add(24 * time.Hour)
+
+	document := Document{ID: "12345678", Content: "hello, world", CreatedAt: created, UpdatedAt: updated,
+		ExpiresAt: &expires, DeleteTokenHash: "delete", EditTokenHash: "edit", Language: "plaintext",
+		ContentType: "text/plain; charset=utf-8"}
+	revisions := []Revision{
+		{Revision: 1, Content: "hello", CreatedAt: created},
+		{Revision: 2, Content: "hello, world", CreatedAt: updated},
+	}
+
+	require.NoError(t, db.ImportDocument(ctx, document, revisions, false))
+
+	// Everything is kept as it was, rather than set by the database
+	imported, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "hello, world", imported.Content)
+	require.True(t, created.Equal(imported.CreatedAt), imported.CreatedAt)
+	require.True(t, updated.Equal(imported.UpdatedAt), imported.UpdatedAt)
+	require.True(t, expires.Equal(*imported.ExpiresAt), imported.ExpiresAt)
+	require.Equal(t, "delete", imported.DeleteTokenHash)
+	require.Equal(t, "edit", imported.EditTokenHash)
+	require.Equal(t, "plaintext", imported.Language)
+	require.Equal(t, "text/plain; charset=utf-8", imported.ContentType)
+
+	importedRevisions, err := db.GetRevisions(ctx, "12345678")
+	require.NoError(t, err)
+	require.Len(t, importedRevisions, 2)
+	require.Equal(t, "hello", importedRevisions[0].Content)
+	require.True(t, created.Equal(importedRevisions[0].CreatedAt), importedRevisions[0].CreatedAt)
+
+	// Imported documents are found by their original timestamps
+	documents, err := db.ListDocuments(ctx, DocumentFilter{CreatedBefore: created.Add(time.Minute)})
+	require.NoError(t, err)
+	require.Len(t, documents, 1)
+
+	// Existing documents are only replaced when asked to
+	replacement := Document{ID: "12345678", Content: "replaced", CreatedAt: created, Files: []File{
+		{Name: "a.txt", Content: "replaced"},
+		{Name: "b.png", Content: "\x89PNG", ContentType: "image/png"},
+	}}
+
+	require.ErrorIs(t, db.ImportDocument(ctx, replacement, nil, false), ErrDocumentExists)
+	require.NoError(t, db.ImportDocument(ctx, replacement, nil, true))
+
+	imported, err = db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "replaced", imported.Content)
+	require.True(t, created.Equal(imported.UpdatedAt), imported.UpdatedAt)
+	require.Nil(t, imported.ExpiresAt)
+	require.Len(t, imported.Files, 2)
+	require.Equal(t, "image/png", imported.Files[1].ContentType)
+
+	// Documents imported without revisions get their content as their first
+	importedRevisions, err = db.GetRevisions(ctx, "12345678")
+	require.NoError(t, err)
+	require.Len(t, importedRevisions, 1)
+	require.Equal(t, "replaced", importedRevisions[0].Content)
+
+	// The replaced document's content was released
+	_, err = db.GetContent(ctx, HashContent("hello"))
+	require.Error(t, err)
+
+	require.NoError(t, db.DeleteDocument(ctx, "12345678"))
+}
@@ -0,0 +1,359 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Migrations are stored as migrations/<dialect>/<version>_<name>.<up|down>.sql.
+// Each file may contain several statements, each ending with a semicolon at the
+// end of a line.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+// Migration is a numbered change to the database schema.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationStatus describes a migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at"` // nil if the migration is pending
+}
+
+// dialect holds the differences between databases that matter to the migrator
+// and the queries shared between backends.
+type dialect struct {
+	name string // Directory holding the dialect's migrations
+
+	// Placeholder for the nth (from 1) query parameter
+	placeholder func(n int) string
+
+	// Appended to a SELECT to lock the rows it reads until the end of the
+	// transaction, where the database supports it
+	forUpdate string
+
+	// Statement storing content ($1) under its hash ($2) with its encoding ($3)
+	// and size ($4), or adding a reference to it if it's already stored
+	holdContent string
+}
+
+var (
+	sqliteDialect   = dialect{"sqlite", numberedPlaceholder, "", upsertContent}
+	postgresDialect = dialect{"postgres", numberedPlaceholder, " FOR UPDATE", upsertContent}
+	mysqlDialect    = dialect{"mysql", func(int) string { return "?" }, " FOR UPDATE", `INSERT INTO contents (content, hash, encoding,
+	size, refs) VALUES (?, ?, ?, ?, 1) ON DUPLICATE KEY UPDATE refs = refs + 1, size = IF(size = 0, VALUES(size), size),
+	encoding = IF(content = '', VALUES(encoding), encoding), content = IF(content = '', VALUES(content), content)`}
+)
+
+// upsertContent is holdContent for databases supporting ON CONFLICT. Content
+// kept in a blob store is saved as an empty string, so it's filled in if the
+// same content is later saved without one.
+const upsertContent = `INSERT INTO contents (content, hash, encoding, size, refs) VALUES ($1, $2, $3, $4, 1)
+ON CONFLICT (hash) DO UPDATE SET refs = contents.refs + 1,
+	size = CASE WHEN contents.size = 0 THEN EXCLUDED.size ELSE contents.size END,
+	content = CASE WHEN contents.content = '' THEN EXCLUDED.content ELSE contents.content END,
+	encoding = CASE WHEN contents.content = '' THEN EXCLUDED.encoding ELSE contents.encoding END`
+
+func numberedPlaceholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+// loadMigrations reads a dialect's migrations, sorted by version.
+func loadMigrations(d dialect) ([]Migration, error) {
+	dir := path.Join("migrations", d.name)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+
+	for _, entry := range entries {
+		base, ok := strings.CutSuffix(entry.Name(), ".sql")
+
+		if !ok {
+			continue
+		}
+
+		ext := path.Ext(base) // .up or .down
+		direction := strings.TrimPrefix(ext, ".")
+		v, name, _ := strings.Cut(strings.TrimSuffix(base, ext), "_")
+		version, err := strconv.Atoi(v)
+
+		if err != nil || (direction != "up" && direction != "down") {
+			return nil, fmt.Errorf("invalid migration file name %s", entry.Name())
+		}
+
+		content, err := fs.ReadFile(migrationFiles, path.Join(dir, entry.Name()))
+
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+
+		if !ok {
+			m = &Migration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+
+		if m.Name != name {
+			return nil, fmt.Errorf("migration %d has conflicting names %s and %s", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", m.Version, m.Name)
+		}
+
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// splitStatements splits a migration into its statements. Comment lines are dropped.
+func splitStatements(migration string) []string {
+	var statements []string
+	var current strings.Builder
+
+	for _, line := range strings.Split(migration, "\n") {
+		trimmed := strings.TrimSpace(line)
+
+		if trimmed == "" || strings.HasPrefix(trimmed, "--") {
+			continue
+		}
+
+		current.WriteString(line + "\n")
+
+		if strings.HasSuffix(trimmed, ";") {
+			statements = append(statements, strings.TrimSuffix(strings.TrimSpace(current.String()), ";"))
+			current.Reset()
+		}
+	}
+
+	if rest := strings.TrimSpace(current.String()); rest != "" {
+		statements = append(statements, rest)
+	}
+
+	return statements
+}
+
+// appliedMigrations creates the schema_migrations table if needed, and returns
+// when each applied migration was applied, by version.
+func appliedMigrations(ctx context.Context, db *sql.DB) (map[int]time.Time, error) {
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version INTEGER PRIMARY KEY,
+	name VARCHAR(255) NOT NULL,
+	applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+)`)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT version, applied_at FROM schema_migrations")
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := map[int]time.Time{}
+
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	return applied, rows.Err()
+}
+
+// runMigration runs one direction of a migration in a transaction, recording
+// the change in schema_migrations. MySQL commits schema changes implicitly, so
+// there a failed migration may be left partly applied.
+func runMigration(ctx context.Context, db *sql.DB, d dialect, m Migration, up bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	script := m.Down
+
+	if up {
+		script = m.Up
+	}
+
+	for _, statement := range splitStatements(script) {
+		if _, err := tx.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("migration %d_%s: %w", m.Version, m.Name, err)
+		}
+	}
+
+	if up {
+		_, err = tx.ExecContext(ctx, "INSERT INTO schema_migrations (version, name) VALUES ("+
+			d.placeholder(1)+", "+d.placeholder(2)+")", m.Version, m.Name)
+	} else {
+		_, err = tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version="+d.placeholder(1), m.Version)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// migrateUp applies every pending migration, oldest first.
+func migrateUp(ctx context.Context, db *sql.DB, d dialect) error {
+	migrations, err := loadMigrations(d)
+
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		if err := runMigration(ctx, db, d, m, true); err != nil {
+			return err
+		}
+
+		log.Info().
+			Int("version", m.Version).
+			Str("name", m.Name).
+			Msg("Applied migration")
+	}
+
+	return nil
+}
+
+// migrateDown reverts the latest steps applied migrations, newest first.
+func migrateDown(ctx context.Context, db *sql.DB, d dialect, steps int) error {
+	migrations, err := loadMigrations(d)
+
+	if err != nil {
+		return err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+
+	if err != nil {
+		return err
+	}
+
+	for i := len(migrations) - 1; i >= 0 && steps > 0; i-- {
+		m := migrations[i]
+
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		if err := runMigration(ctx, db, d, m, false); err != nil {
+			return err
+		}
+
+		log.Info().
+			Int("version", m.Version).
+			Str("name", m.Name).
+			Msg("Reverted migration")
+
+		steps--
+	}
+
+	return nil
+}
+
+// migrationStatus lists every known migration and whether it has been applied.
+func migrationStatus(ctx context.Context, db *sql.DB, d dialect) ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(d)
+
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := appliedMigrations(ctx, db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+
+	for _, m := range migrations {
+		s := MigrationStatus{Version: m.Version, Name: m.Name}
+
+		if appliedAt, ok := applied[m.Version]; ok {
+			s.AppliedAt = &appliedAt
+		}
+
+		status = append(status, s)
+	}
+
+	return status, nil
+}
@@ -0,0 +1,194 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSQLite(t *testing.T) *SQLite {
+	db, err := NewSQLite(&url.URL{Host: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+
+	t.Cleanup(func() { db.Close() })
+
+	return db.(*SQLite)
+}
+
+func TestSplitStatements(t *testing.T) {
+	statements := splitStatements(`-- A comment
+CREATE TABLE a (
+    id TEXT
+);
+
+ALTER TABLE a ADD COLUMN b TEXT;
+DROP TABLE c`)
+
+	require.Equal(t, []string{"CREATE TABLE a (\n    id TEXT\n)", "ALTER TABLE a ADD COLUMN b TEXT", "DROP TABLE c"},
+		statements)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	for _, d := range []dialect{sqliteDialect, postgresDialect, mysqlDialect} {
+		migrations, err := loadMigrations(d)
+		require.NoError(t, err, d.name)
+		require.NotEmpty(t, migrations, d.name)
+
+		for i, m := range migrations {
+			require.Equal(t, i+1, m.Version, d.name)
+			require.NotEmpty(t, splitStatements(m.Up), d.name)
+			require.NotEmpty(t, splitStatements(m.Down), d.name)
+		}
+	}
+}
+
+func TestMigrateUpAndDown(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+
+	require.NoError(t, db.Migrate(ctx))
+	require.NoError(t, db.Migrate(ctx)) // Migrating again does nothing
+
+	status, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+
+	for _, s := range status {
+		require.NotNil(t, s.AppliedAt, s.Name)
+	}
+
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: "test"}))
+
+	// Revert the latest migration
+	require.NoError(t, db.MigrateDown(ctx, 1))
+
+	status, err = db.MigrationStatus(ctx)
+	require.NoError(t, err)
+	require.Nil(t, status[len(status)-1].AppliedAt)
+	require.NotNil(t, status[len(status)-2].AppliedAt)
+
+	// Shared content is moved back into the documents using it
+	contents := slices.IndexFunc(status, func(s MigrationStatus) bool { return s.Name == "create_contents" })
+	require.NoError(t, db.MigrateDown(ctx, len(status)-1-contents))
+
+	var content string
+	require.NoError(t, db.QueryRow("SELECT content FROM documents WHERE id='12345678'").Scan(&content))
+	require.Equal(t, "test", content)
+
+	// Revert back to before the rename of usdated_at
+	rename := slices.IndexFunc(status, func(s MigrationStatus) bool { return s.Name == "rename_usdated_at" })
+	require.NoError(t, db.MigrateDown(ctx, contents-rename))
+
+	var n int
+	require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM documents WHERE usdated_at IS NOT NULL").Scan(&n))
+	require.Equal(t, 1, n)
+
+	// Revert everything, then apply it all again
+	require.NoError(t, db.MigrateDown(ctx, len(status)))
+	require.NoError(t, db.Migrate(ctx))
+
+	_, err = db.GetDocument(ctx, "12345678")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+// TestMigrateLegacyDatabase tests upgrading a database created before
+// migrations were versioned
+func TestMigrateLegacyDatabase(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+
+	_, err := db.Exec(`
+CREATE TABLE IF NOT EXISTS documents (
+    id TEXT PRIMARY KEY,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    usdated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+INSERT INTO documents (id, content) VALUES ('12345678', 'test');`)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Migrate(ctx))
+
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "test", document.Content)
+	require.False(t, document.UpdatedAt.IsZero())
+	require.Equal(t, HashContent("test"), document.ContentHash)
+
+	// Existing documents get their content as their first revision
+	revisions, err := db.GetRevisions(ctx, "12345678")
+	require.NoError(t, err)
+	require.Len(t, revisions, 1)
+	require.Equal(t, "test", revisions[0].Content)
+
+	// Their content isn't shared, but they can still be deleted
+	require.NoError(t, db.DeleteDocument(ctx, "12345678"))
+}
+
+func TestMigrateDocumentLanguage(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+
+	require.NoError(t, db.Migrate(ctx))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "12345678", Content: "package main", Language: "Go"}))
+
+	document, err := db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Equal(t, "Go", document.Language)
+
+	// Documents from before languages were stored have none, so it's guessed when they're viewed
+	status, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+
+	language := slices.IndexFunc(status, func(s MigrationStatus) bool { return s.Name == "add_document_language" })
+	require.NoError(t, db.MigrateDown(ctx, len(status)-language))
+	require.NoError(t, db.Migrate(ctx))
+
+	document, err = db.GetDocument(ctx, "12345678")
+	require.NoError(t, err)
+	require.Empty(t, document.Language)
+}
+
+func TestMigrateContentSize(t *testing.T) {
+	ctx := context.Background()
+	db := newTestSQLite(t)
+
+	require.NoError(t, db.Migrate(ctx))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "text0000", Content: "héllo"}))
+	require.NoError(t, db.CreateDocument(ctx, Document{ID: "binary00", Content: "\x89PNG\x00"}))
+
+	// Sizes of content stored before they were recorded are worked out from it,
+	// in bytes and as decoded
+	status, err := db.MigrationStatus(ctx)
+	require.NoError(t, err)
+
+	size := slices.IndexFunc(status, func(s MigrationStatus) bool { return s.Name == "add_content_size" })
+	require.NoError(t, db.MigrateDown(ctx, len(status)-size))
+	require.NoError(t, db.Migrate(ctx))
+
+	for id, size := range map[string]int64{"text0000": 6, "binary00": 5} {
+		document, err := db.GetDocument(ctx, id)
+		require.NoError(t, err)
+		require.Equal(t, size, document.Size, id)
+	}
+}
@@ -0,0 +1,7 @@
+-- IF NOT EXISTS adopts databases created before migrations were versioned
+CREATE TABLE IF NOT EXISTS documents (
+	id VARCHAR(255) PRIMARY KEY,
+	content TEXT NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP
+);
@@ -0,0 +1,3 @@
+ALTER TABLE documents
+	ADD COLUMN expires_at DATETIME NULL,
+	ADD COLUMN burn_after_read BOOLEAN NOT NULL DEFAULT FALSE;
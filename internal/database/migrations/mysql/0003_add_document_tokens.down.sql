@@ -0,0 +1,3 @@
+ALTER TABLE documents
+	DROP COLUMN edit_token_hash,
+	DROP COLUMN delete_token_hash;
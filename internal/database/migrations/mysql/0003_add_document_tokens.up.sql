@@ -0,0 +1,3 @@
+ALTER TABLE documents
+	ADD COLUMN delete_token_hash VARCHAR(64) NOT NULL DEFAULT '',
+	ADD COLUMN edit_token_hash VARCHAR(64) NOT NULL DEFAULT '';
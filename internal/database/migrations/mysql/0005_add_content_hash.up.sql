@@ -0,0 +1,2 @@
+ALTER TABLE documents ADD COLUMN content_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE document_revisions ADD COLUMN content_hash VARCHAR(64) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Move shared content back into the documents and revisions using it
+UPDATE documents d JOIN contents c ON c.hash = d.content_hash SET d.content = c.content WHERE c.content <> '';
+UPDATE document_revisions r JOIN contents c ON c.hash = r.content_hash SET r.content = c.content WHERE c.content <> '';
+
+DROP TABLE contents;
@@ -0,0 +1,6 @@
+-- Content is stored once per hash, counting the documents and revisions using it
+CREATE TABLE contents (
+	hash VARCHAR(64) PRIMARY KEY,
+	content LONGTEXT NOT NULL,
+	refs INT NOT NULL DEFAULT 0
+);
@@ -0,0 +1 @@
+ALTER TABLE documents ADD COLUMN language varchar(255) NOT NULL DEFAULT '';
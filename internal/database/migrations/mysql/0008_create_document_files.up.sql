@@ -0,0 +1,9 @@
+-- Documents with several files list each of them here, in order
+CREATE TABLE document_files (
+	document_id VARCHAR(255) NOT NULL,
+	position INT NOT NULL,
+	name VARCHAR(255) NOT NULL,
+	language VARCHAR(255) NOT NULL DEFAULT '',
+	content_hash VARCHAR(64) NOT NULL,
+	PRIMARY KEY (document_id, position)
+);
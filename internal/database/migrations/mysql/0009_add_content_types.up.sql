@@ -0,0 +1,4 @@
+-- Content that isn't text is stored base64 encoded, and its documents and files record its type
+ALTER TABLE contents ADD COLUMN encoding VARCHAR(16) NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN content_type VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE document_files ADD COLUMN content_type VARCHAR(255) NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+-- IF NOT EXISTS adopts databases created before migrations were versioned
+CREATE TABLE IF NOT EXISTS documents (
+	id varchar(255) PRIMARY KEY,
+	content text NOT NULL,
+	created_at timestamp with time zone DEFAULT now(),
+	updated_at timestamp with time zone DEFAULT now()
+);
@@ -0,0 +1,3 @@
+ALTER TABLE documents
+	DROP COLUMN burn_after_read,
+	DROP COLUMN expires_at;
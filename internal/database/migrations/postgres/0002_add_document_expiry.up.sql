@@ -0,0 +1,3 @@
+ALTER TABLE documents
+	ADD COLUMN expires_at timestamp with time zone,
+	ADD COLUMN burn_after_read boolean NOT NULL DEFAULT false;
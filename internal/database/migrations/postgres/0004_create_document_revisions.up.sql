@@ -0,0 +1,11 @@
+CREATE TABLE document_revisions (
+	document_id varchar(255) NOT NULL,
+	revision integer NOT NULL,
+	content text NOT NULL,
+	created_at timestamp with time zone DEFAULT now(),
+	PRIMARY KEY (document_id, revision)
+);
+
+-- Existing documents start out with their current content as the first revision
+INSERT INTO document_revisions (document_id, revision, content, created_at)
+SELECT id, 1, content, created_at FROM documents;
@@ -0,0 +1,2 @@
+ALTER TABLE document_revisions DROP COLUMN content_hash;
+ALTER TABLE documents DROP COLUMN content_hash;
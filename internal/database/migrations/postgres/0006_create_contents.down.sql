@@ -0,0 +1,5 @@
+-- Move shared content back into the documents and revisions using it
+UPDATE documents d SET content = c.content FROM contents c WHERE c.hash = d.content_hash AND c.content <> '';
+UPDATE document_revisions r SET content = c.content FROM contents c WHERE c.hash = r.content_hash AND c.content <> '';
+
+DROP TABLE contents;
@@ -0,0 +1,6 @@
+-- Content is stored once per hash, counting the documents and revisions using it
+CREATE TABLE contents (
+	hash varchar(64) PRIMARY KEY,
+	content text NOT NULL,
+	refs integer NOT NULL DEFAULT 0
+);
@@ -0,0 +1,9 @@
+-- Documents with several files list each of them here, in order
+CREATE TABLE document_files (
+	document_id varchar(255) NOT NULL,
+	position integer NOT NULL,
+	name varchar(255) NOT NULL,
+	language varchar(255) NOT NULL DEFAULT '',
+	content_hash varchar(64) NOT NULL,
+	PRIMARY KEY (document_id, position)
+);
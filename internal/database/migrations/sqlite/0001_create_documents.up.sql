@@ -0,0 +1,7 @@
+-- IF NOT EXISTS adopts databases created before migrations were versioned
+CREATE TABLE IF NOT EXISTS documents (
+    id TEXT PRIMARY KEY,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    usdated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+ALTER TABLE documents DROP COLUMN burn_after_read;
+ALTER TABLE documents DROP COLUMN expires_at;
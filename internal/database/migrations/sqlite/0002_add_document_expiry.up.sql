@@ -0,0 +1,2 @@
+ALTER TABLE documents ADD COLUMN expires_at TIMESTAMP;
+ALTER TABLE documents ADD COLUMN burn_after_read BOOLEAN NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE documents DROP COLUMN edit_token_hash;
+ALTER TABLE documents DROP COLUMN delete_token_hash;
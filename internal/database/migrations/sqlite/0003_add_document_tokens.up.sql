@@ -0,0 +1,2 @@
+ALTER TABLE documents ADD COLUMN delete_token_hash TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN edit_token_hash TEXT NOT NULL DEFAULT '';
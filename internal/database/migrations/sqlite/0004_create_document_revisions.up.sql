@@ -0,0 +1,11 @@
+CREATE TABLE document_revisions (
+    document_id TEXT NOT NULL,
+    revision INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (document_id, revision)
+);
+
+-- Existing documents start out with their current content as the first revision
+INSERT INTO document_revisions (document_id, revision, content, created_at)
+SELECT id, 1, content, created_at FROM documents;
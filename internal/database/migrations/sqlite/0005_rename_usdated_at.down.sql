@@ -0,0 +1 @@
+ALTER TABLE documents RENAME COLUMN updated_at TO usdated_at;
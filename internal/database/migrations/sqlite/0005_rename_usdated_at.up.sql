@@ -0,0 +1,2 @@
+-- The column was misspelled when SQLite support was first added
+ALTER TABLE documents RENAME COLUMN usdated_at TO updated_at;
@@ -0,0 +1,7 @@
+-- Move shared content back into the documents and revisions using it
+UPDATE documents SET content = (SELECT content FROM contents WHERE hash = documents.content_hash)
+WHERE content_hash IN (SELECT hash FROM contents WHERE content <> '');
+UPDATE document_revisions SET content = (SELECT content FROM contents WHERE hash = document_revisions.content_hash)
+WHERE content_hash IN (SELECT hash FROM contents WHERE content <> '');
+
+DROP TABLE contents;
@@ -0,0 +1,6 @@
+-- Content is stored once per hash, counting the documents and revisions using it
+CREATE TABLE contents (
+    hash TEXT PRIMARY KEY,
+    content TEXT NOT NULL,
+    refs INTEGER NOT NULL DEFAULT 0
+);
@@ -0,0 +1 @@
+ALTER TABLE documents ADD COLUMN language TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Release the content of every file; documents keep the content of their first
+UPDATE contents SET refs = refs - (SELECT COUNT(*) FROM document_files WHERE content_hash = contents.hash);
+DELETE FROM contents WHERE refs <= 0;
+
+DROP TABLE document_files;
@@ -0,0 +1,9 @@
+-- Documents with several files list each of them here, in order
+CREATE TABLE document_files (
+    document_id TEXT NOT NULL,
+    position INTEGER NOT NULL,
+    name TEXT NOT NULL,
+    language TEXT NOT NULL DEFAULT '',
+    content_hash TEXT NOT NULL,
+    PRIMARY KEY (document_id, position)
+);
@@ -0,0 +1,4 @@
+-- Content that isn't text is left base64 encoded
+ALTER TABLE document_files DROP COLUMN content_type;
+ALTER TABLE documents DROP COLUMN content_type;
+ALTER TABLE contents DROP COLUMN encoding;
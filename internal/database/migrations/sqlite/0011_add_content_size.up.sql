@@ -0,0 +1,5 @@
+-- Content records its size in bytes, as decoded, so documents can be measured without reading it
+ALTER TABLE contents ADD COLUMN size INTEGER NOT NULL DEFAULT 0;
+UPDATE contents SET size = CASE WHEN encoding = 'base64'
+    THEN LENGTH(content) * 3 / 4 - (LENGTH(content) - LENGTH(RTRIM(content, '=')))
+    ELSE LENGTH(CAST(content AS BLOB)) END;
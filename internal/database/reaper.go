@@ -0,0 +1,116 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Reaper periodically deletes documents that have passed their expiry time.
+// Documents from before expiry times were stored have none, so they're deleted
+// once they're older than the maximum age instead.
+type Reaper struct {
+	db       Database
+	interval time.Duration
+	maxAge   time.Duration // 0 if documents are kept forever
+	keep     []string      // IDs of documents that never expire, like custom documents
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewReaper creates a Reaper that checks for expired documents every interval.
+// Documents without an expiry time are deleted once they're older than maxAge,
+// unless their ID is in keep.
+func NewReaper(db Database, interval, maxAge time.Duration, keep []string) *Reaper {
+	return &Reaper{
+		db:       db,
+		interval: interval,
+		maxAge:   maxAge,
+		keep:     keep,
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// Start runs the reaper in the background until Stop is called. An initial
+// purge is performed immediately.
+func (r *Reaper) Start() {
+	go func() {
+		defer close(r.done)
+
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+
+		for {
+			r.reap()
+
+			select {
+			case <-r.stop:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop signals the reaper to exit and waits for any in-progress purge to finish.
+func (r *Reaper) Stop() {
+	close(r.stop)
+	<-r.done
+}
+
+func (r *Reaper) reap() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+	defer cancel()
+
+	now := time.Now()
+	n, err := r.db.DeleteExpiredDocuments(ctx, now)
+
+	if err != nil {
+		log.Error().
+			Err(err).
+			Msg("Failed to delete expired documents")
+		return
+	}
+
+	if r.maxAge > 0 {
+		old, err := r.db.DeleteDocuments(ctx, DocumentFilter{
+			NeverExpires:  true,
+			CreatedBefore: now.Add(-r.maxAge),
+			Except:        r.keep,
+		})
+
+		if err != nil {
+			log.Error().
+				Err(err).
+				Msg("Failed to delete documents without an expiry time")
+			return
+		}
+
+		n += old
+	}
+
+	if n > 0 {
+		log.Info().
+			Int64("count", n).
+			Msg("Deleted expired documents")
+	}
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package database_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReaper(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.DeleteExpiredDocumentsReturns(3, nil)
+
+	reaper := database.NewReaper(mockDB, time.Hour, 0, nil)
+	reaper.Start()
+
+	// The first purge happens as soon as the reaper starts
+	require.Eventually(t, func() bool {
+		return mockDB.DeleteExpiredDocumentsCallCount() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	reaper.Stop()
+
+	_, now := mockDB.DeleteExpiredDocumentsArgsForCall(0)
+	require.WithinDuration(t, time.Now(), now, time.Second)
+	require.Equal(t, 1, mockDB.DeleteExpiredDocumentsCallCount())
+
+	// Without a maximum age, documents without an expiry time are kept
+	require.Zero(t, mockDB.DeleteDocumentsCallCount())
+}
+
+func TestReaperMaxAge(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+
+	reaper := database.NewReaper(mockDB, time.Hour, 720*time.Hour, []string{"about"})
+	reaper.Start()
+
+	require.Eventually(t, func() bool {
+		return mockDB.DeleteDocumentsCallCount() == 1
+	}, time.Second, 10*time.Millisecond)
+
+	reaper.Stop()
+
+	// Documents from before expiry times were stored are deleted once they're
+	// older than the maximum age, except custom documents
+	_, filter := mockDB.DeleteDocumentsArgsForCall(0)
+	require.True(t, filter.NeverExpires)
+	require.WithinDuration(t, time.Now().Add(-720*time.Hour), filter.CreatedBefore, time.Second)
+	require.Equal(t, []string{"about"}, filter.Except)
+}
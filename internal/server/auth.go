@@ -0,0 +1,66 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+)
+
+// basicAuth only lets in the users it's given. Users with read permission can
+// view documents, but only those with create permission can create, edit or
+// delete them.
+func basicAuth(realm string, users *util.Users) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name, password, ok := r.BasicAuth()
+			user, authenticated := users.Authenticate(name, password)
+
+			if !ok || !authenticated {
+				w.Header().Add("WWW-Authenticate", `Basic realm="`+realm+`"`)
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+
+			if user.Permission < util.PermissionCreate && !isReadOnly(r.Method) {
+				util.WriteError(w, http.StatusForbidden, errors.New("you can only view documents"))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// addConfigUser adds the user in Config.Username and Config.Password, who can
+// create documents.
+func (s *Server) addConfigUser(users *util.Users) error {
+	hash, err := util.HashPassword(s.Config.Password)
+
+	if err != nil {
+		return err
+	}
+
+	return users.Add(util.User{Name: s.Config.Username, Hash: hash, Permission: util.PermissionCreate})
+}
+
+// isReadOnly reports whether requests with a method never change anything.
+func isReadOnly(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
@@ -0,0 +1,114 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newAuthServer(t *testing.T, cfg *config.Cfg) *server.Server {
+	users := util.NewUsers()
+
+	for name, permission := range map[string]util.Permission{
+		"viewer": util.PermissionRead,
+		"writer": util.PermissionCreate,
+	} {
+		hash, err := bcrypt.GenerateFromPassword([]byte(name+"-password"), bcrypt.MinCost)
+		require.NoError(t, err)
+		require.NoError(t, users.Add(util.User{Name: name, Hash: string(hash), Permission: permission}))
+	}
+
+	db := &databasefakes.FakeDatabase{}
+	db.GetDocumentReturns(database.Document{ID: "12345678", Content: "test"}, nil)
+
+	s := server.NewServer(cfg, db)
+	s.Users = users
+	s.MountMiddleware()
+	s.MountHandlers()
+
+	return s
+}
+
+// authRequest returns a request reading a document, or creating one if method
+// is POST, as user.
+func authRequest(method, user string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678", nil)
+
+	if method == http.MethodPost {
+		req, _ = http.NewRequest(http.MethodPost, "/api/", strings.NewReader(`{"content": "test"}`))
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if user != "" {
+		req.SetBasicAuth(user, user+"-password")
+	}
+
+	return req
+}
+
+func TestBasicAuth(t *testing.T) {
+	s := newAuthServer(t, &mockConfig)
+
+	// Everyone has to log in
+	res := executeRequest(authRequest(http.MethodGet, ""), s)
+	checkResponseCode(t, http.StatusUnauthorized, res.Code)
+	require.Equal(t, `Basic realm="spacebin"`, res.Header().Get("WWW-Authenticate"))
+
+	wrong, _ := http.NewRequest(http.MethodGet, "/api/12345678", nil)
+	wrong.SetBasicAuth("writer", "viewer-password")
+	checkResponseCode(t, http.StatusUnauthorized, executeRequest(wrong, s).Code)
+
+	// Viewers can read documents, but not create them
+	checkResponseCode(t, http.StatusOK, executeRequest(authRequest(http.MethodGet, "viewer"), s).Code)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(authRequest(http.MethodPost, "viewer"), s).Code)
+
+	// Writers can do both
+	checkResponseCode(t, http.StatusOK, executeRequest(authRequest(http.MethodGet, "writer"), s).Code)
+	checkResponseCode(t, http.StatusOK, executeRequest(authRequest(http.MethodPost, "writer"), s).Code)
+}
+
+func TestBasicAuthConfigUser(t *testing.T) {
+	cfg := mockConfig
+	cfg.Username, cfg.Password = "admin", "hunter2"
+
+	s := newAuthServer(t, &cfg)
+
+	// The user from the config can create documents alongside those from the users file
+	req := authRequest(http.MethodPost, "")
+	req.SetBasicAuth("admin", "hunter2")
+	checkResponseCode(t, http.StatusOK, executeRequest(req, s).Code)
+	checkResponseCode(t, http.StatusForbidden, executeRequest(authRequest(http.MethodPost, "viewer"), s).Code)
+
+	// Credentials are never shown to anyone
+	req = authRequest(http.MethodGet, "writer")
+	req.URL.Path = "/config"
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Code)
+	require.NotContains(t, res.Body.String(), "hunter2")
+	require.NotContains(t, res.Body.String(), "password")
+}
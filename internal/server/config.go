@@ -18,12 +18,43 @@ package server
 
 import (
 	"net/http"
+	"strings"
 
 	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 )
 
+// customDocumentIDs returns the IDs of custom documents, leaving out the paths
+// of the files any are loaded from.
+func customDocumentIDs(documents []string) []string {
+	var ids []string
+
+	for _, entry := range documents {
+		name, _, _ := strings.Cut(entry, "=")
+		ids = append(ids, strings.TrimSpace(name))
+	}
+
+	return ids
+}
+
 func (s *Server) GetConfig(w http.ResponseWriter, r *http.Request) {
-	if err := util.WriteJSON(w, http.StatusOK, s.Config); err != nil {
+	if err := util.WriteJSON(w, http.StatusOK, api.Config{
+		Host:                  s.Config.Host,
+		Port:                  s.Config.Port,
+		CompressionLevel:      s.Config.CompressionLevel,
+		Ratelimiter:           s.Config.Ratelimiter,
+		Headless:              s.Config.Headless,
+		Analytics:             s.Config.Analytics,
+		Theme:                 s.Config.Theme,
+		ContentSecurityPolicy: s.Config.ContentSecurityPolicy,
+		IDLength:              s.Config.IDLength,
+		IDType:                s.Config.IDType,
+		MaxSize:               s.Config.MaxSize,
+		ExpirationAge:         s.Config.ExpirationAge,
+		Documents:             customDocumentIDs(s.Config.Documents),
+		WatchDocuments:        s.Config.WatchDocuments,
+		Themes:                util.Themes(),
+	}); err != nil {
 		util.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -26,6 +26,8 @@ import (
 	"github.com/lukewhrit/spacebin/internal/config"
 	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
 	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 	"github.com/stretchr/testify/require"
 )
 
@@ -45,6 +47,7 @@ var mockConfig = config.Cfg{
 	ExpirationAge:         720,
 	ContentSecurityPolicy: "default-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'self'; script-src 'self' 'unsafe-inline'; style-src 'self' 'unsafe-inline';",
 	Headless:              false,
+	Theme:                 "auto",
 }
 
 // executeRequest, creates a new ResponseRecorder
@@ -82,4 +85,35 @@ func TestConfig(t *testing.T) {
 	json.Unmarshal(x, &body)
 
 	require.Equal(t, mockConfig, body.Payload)
+
+	// Clients are told which themes they can pick from
+	var themes struct {
+		Payload api.Config
+	}
+	json.Unmarshal(x, &themes)
+
+	require.Equal(t, util.Themes(), themes.Payload.Themes)
+}
+
+// TestConfigDocumentPaths tests that the paths custom documents are loaded from
+// aren't shown to clients
+func TestConfigDocumentPaths(t *testing.T) {
+	config := mockConfig
+	config.Documents = []string{"about=./docs/about.md", "faq"}
+
+	s := server.NewServer(&config, &databasefakes.FakeDatabase{})
+	s.MountHandlers()
+
+	req, _ := http.NewRequest("GET", "/config", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.NotContains(t, res.Body.String(), "./docs/about.md")
+
+	var body struct {
+		Payload api.Config
+	}
+	json.Unmarshal(res.Body.Bytes(), &body)
+
+	require.Equal(t, []string{"about", "faq"}, body.Payload.Documents)
 }
@@ -17,55 +17,211 @@
 package server
 
 import (
+	"errors"
 	"fmt"
+	"html/template"
+	"io"
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
+	"github.com/lukewhrit/spacebin/internal/database"
 	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 )
 
+// documentTokens holds the tokens issued to whoever creates a document.
+type documentTokens struct {
+	delete, edit string
+}
+
+// tokensCookie holds the tokens of a document created from a browser until the
+// document's page shows them, since the redirect to it can't.
+const tokensCookie = "spacebin_tokens"
+
+// setTokensCookie keeps a new document's tokens for its page to show.
+func setTokensCookie(w http.ResponseWriter, r *http.Request, id string, tokens documentTokens) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     tokensCookie,
+		Value:    tokens.delete + "." + tokens.edit, // Tokens are base64url, so never contain "."
+		Path:     "/" + id,
+		MaxAge:   300,
+		Secure:   r.TLS != nil,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// takeTokens returns the tokens kept by setTokensCookie for a document, if they
+// are its own, and forgets them so they're only shown once.
+func takeTokens(w http.ResponseWriter, r *http.Request, document database.Document) (documentTokens, bool) {
+	cookie, err := r.Cookie(tokensCookie)
+
+	if err != nil {
+		return documentTokens{}, false
+	}
+
+	http.SetCookie(w, &http.Cookie{Name: tokensCookie, Path: "/" + document.ID, MaxAge: -1})
+
+	deleteToken, editToken, _ := strings.Cut(cookie.Value, ".")
+
+	if !util.CheckToken(deleteToken, document.DeleteTokenHash) || !util.CheckToken(editToken, document.EditTokenHash) {
+		return documentTokens{}, false
+	}
+
+	return documentTokens{delete: deleteToken, edit: editToken}, true
+}
+
 // createDocument handles the shared logic between the CreateDocument and StaticCreateDocument handlers.
-func createDocument(s *Server, w http.ResponseWriter, r *http.Request) (string, error) {
-	// Parse body from HTML request
-	body, err := util.HandleBody(s.Config.MaxSize, r)
+// It returns the new document's ID and the tokens needed to delete and edit it.
+func createDocument(s *Server, w http.ResponseWriter, r *http.Request) (string, documentTokens, error) {
+	var body api.CreateRequest
+	var content io.Reader
+	var err error
+
+	// Raw bodies are streamed straight into storage if it can take them, and only
+	// their start is read into memory. Only blob stores can, since databases need
+	// a document's whole content to insert it, so without one, and for anything
+	// other than a raw body, the body is read as a whole, up to MaxSize.
+	streamer, stream := database.Streamer(s.Database)
+	stream = stream && util.IsRawBody(r)
+
+	if stream {
+		body, content, err = util.HandleRawBody(s.Config.MaxSize, w, r)
+	} else {
+		body, err = util.HandleBody(s.Config.MaxSize, w, r)
+	}
 
 	if err != nil {
-		util.WriteError(w, http.StatusInternalServerError, err)
-		return "", err
+		return "", documentTokens{}, fmt.Errorf("bad request: %w", err)
 	}
 
 	// Validate fields of body
 	if err := util.ValidateBody(s.Config.MaxSize, body); err != nil {
-		return "", fmt.Errorf("bad request: %v", err)
+		return "", documentTokens{}, fmt.Errorf("bad request: %v", err)
 	}
 
-	// Generate ID for document
+	// Generate ID and tokens for document
 	id := util.GenerateID(s.Config.IDType, s.Config.IDLength)
+	deleteToken, err := util.GenerateToken()
+
+	if err != nil {
+		return "", documentTokens{}, err
+	}
+
+	editToken, err := util.GenerateToken()
+
+	if err != nil {
+		return "", documentTokens{}, err
+	}
+
+	document := database.Document{
+		ID:              id,
+		Content:         body.Content,
+		ExpiresAt:       documentExpiry(s.Config.ExpirationAge, body.ExpiresIn),
+		BurnAfterRead:   body.BurnAfterRead,
+		DeleteTokenHash: util.HashToken(deleteToken),
+		EditTokenHash:   util.HashToken(editToken),
+	}
+
+	// Use the language the uploader picked, otherwise guess it from the content
+	language, ok := util.Language(body.Language)
+
+	if !ok {
+		language = util.DetectLanguage(body.Content)
+	}
+
+	document.Language, document.ContentType = language, util.TextContentType
+
+	for _, file := range body.Files {
+		contentType := file.ContentType
+
+		if contentType == "" {
+			contentType = util.TextContentType
+		}
+
+		// Only text has a language to be highlighted in
+		language := ""
+
+		if util.IsText(contentType) {
+			var ok bool
+			language, ok = util.Language(file.Language)
+
+			if !ok {
+				language = util.DetectFileLanguage(file.Name, file.Content)
+			}
+		}
+
+		document.Files = append(document.Files, database.File{
+			Name:        file.Name,
+			Language:    language,
+			ContentType: contentType,
+			Content:     file.Content,
+		})
+	}
+
+	// Documents with several files are shown as their first anywhere only one fits
+	if len(document.Files) > 0 {
+		first := document.Files[0]
+		document.Content, document.Language, document.ContentType = first.Content, first.Language, first.ContentType
+	}
 
 	// Add document in database
-	if err := s.Database.CreateDocument(
-		r.Context(),
-		id,
-		body.Content,
-	); err != nil {
-		return "", err
+	if stream {
+		err = streamer.CreateDocumentFrom(r.Context(), document, content)
+	} else {
+		err = s.Database.CreateDocument(r.Context(), document)
+	}
+
+	if err != nil {
+		return "", documentTokens{}, err
+	}
+
+	return id, documentTokens{delete: deleteToken, edit: editToken}, nil
+}
+
+// documentExpiry works out when a document should expire, given the lifetime
+// requested by the uploader in seconds. Uploaders can choose a shorter lifetime
+// than the server-wide expiration age (in hours), but never a longer one. If
+// neither is set the document never expires and nil is returned.
+func documentExpiry(expirationAge, expiresIn int64) *time.Time {
+	maxAge := time.Duration(expirationAge) * time.Hour
+	age := time.Duration(min(expiresIn, int64(math.MaxInt64/time.Second))) * time.Second
+
+	if maxAge > 0 && (age == 0 || age > maxAge) {
+		age = maxAge
+	}
+
+	if age <= 0 {
+		return nil
 	}
 
-	return id, nil
+	expiresAt := time.Now().UTC().Add(age).Truncate(time.Second)
+	return &expiresAt
+}
+
+// createStatus picks the status to respond with when a document can't be created.
+func createStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &tooLarge):
+		return http.StatusRequestEntityTooLarge
+	case strings.Contains(err.Error(), "bad request:"):
+		return http.StatusBadRequest
+	}
+
+	return http.StatusInternalServerError
 }
 
 func (s *Server) CreateDocument(w http.ResponseWriter, r *http.Request) {
 	// Create document, then pull it from the database
-	id, err := createDocument(s, w, r)
+	id, tokens, err := createDocument(s, w, r)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "bad request:") {
-			util.WriteError(w, http.StatusBadRequest, err)
-			return
-		} else {
-			util.WriteError(w, http.StatusInternalServerError, err)
-			return
-		}
+		util.WriteError(w, createStatus(err), err)
+		return
 	}
 
 	document, err := s.Database.GetDocument(r.Context(), id)
@@ -75,25 +231,61 @@ func (s *Server) CreateDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Respond to request with Document object
-	if err := util.WriteJSON(w, http.StatusOK, document); err != nil {
+	// Respond to request with Document object and its tokens
+	if err := util.WriteJSON(w, http.StatusOK, api.CreateResponse{
+		Document:    apiDocument(document),
+		DeleteToken: tokens.delete,
+		EditToken:   tokens.edit,
+	}); err != nil {
 		util.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
 }
 
+// acceptedFormat picks the format to respond to a request for the website in.
+// Browsers ask for HTML, and so are sent web pages. Clients that ask for JSON get
+// the same response as from the API, and anything else, like curl, just text.
+// Requests that don't say what they accept are treated as coming from browsers.
+func acceptedFormat(r *http.Request) string {
+	accept := r.Header.Get("Accept")
+
+	switch {
+	case accept == "" || strings.Contains(accept, "text/html"):
+		return "html"
+	case strings.Contains(accept, "application/json"):
+		return "json"
+	}
+
+	return "text"
+}
+
+// documentURL is the full URL of a document, on the host it was requested from.
+func documentURL(r *http.Request, id string) string {
+	scheme := "http"
+
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+
+	return fmt.Sprintf("%s://%s/%s", scheme, r.Host, id)
+}
+
 func (s *Server) StaticCreateDocument(w http.ResponseWriter, r *http.Request) {
+	switch acceptedFormat(r) {
+	case "json":
+		s.CreateDocument(w, r)
+		return
+	case "text":
+		s.textCreateDocument(w, r)
+		return
+	}
+
 	// Create document, then pull it from the database
-	id, err := createDocument(s, w, r)
+	id, tokens, err := createDocument(s, w, r)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "bad request:") {
-			util.RenderError(&resources, w, http.StatusBadRequest, err)
-			return
-		} else {
-			util.RenderError(&resources, w, http.StatusInternalServerError, err)
-			return
-		}
+		util.RenderError(&resources, w, createStatus(err), err)
+		return
 	}
 
 	document, err := s.Database.GetDocument(r.Context(), id)
@@ -103,6 +295,48 @@ func (s *Server) StaticCreateDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Redirect to document view page
+	// Viewing a burn after reading document would delete it, so show its link instead
+	if document.BurnAfterRead {
+		t, err := template.ParseFS(resources, "web/burn.html")
+
+		if err != nil {
+			util.RenderError(&resources, w, http.StatusInternalServerError, err)
+			return
+		}
+
+		// Nothing else will show the document's tokens
+		w.Header().Set("Cache-Control", "no-store")
+
+		if err := t.Execute(w, map[string]interface{}{
+			"ID":          document.ID,
+			"Host":        r.Host,
+			"DeleteToken": tokens.delete,
+			"EditToken":   tokens.edit,
+		}); err != nil {
+			util.RenderError(&resources, w, http.StatusInternalServerError, err)
+		}
+
+		return
+	}
+
+	// Redirect to document view page, which shows the document's tokens once
+	setTokensCookie(w, r, document.ID, tokens)
 	http.Redirect(w, r, fmt.Sprintf("/%s", document.ID), http.StatusMovedPermanently)
 }
+
+// textCreateDocument creates a document for command line clients, responding
+// with only its URL. Its tokens are sent in the same headers they're used with.
+func (s *Server) textCreateDocument(w http.ResponseWriter, r *http.Request) {
+	id, tokens, err := createDocument(s, w, r)
+
+	if err != nil {
+		http.Error(w, err.Error(), createStatus(err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set(api.DeleteTokenHeader, tokens.delete)
+	w.Header().Set(api.EditTokenHeader, tokens.edit)
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, documentURL(r, id))
+}
@@ -18,16 +18,23 @@ package server_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/lukewhrit/spacebin/internal/database"
 	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
 	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 )
@@ -36,10 +43,12 @@ type CreateDocumentSuite struct {
 	suite.Suite
 
 	srv *server.Server
+	db  *databasefakes.FakeDatabase
 }
 
 func (s *CreateDocumentSuite) SetupTest() {
 	mockDB := &databasefakes.FakeDatabase{}
+	s.db = mockDB
 
 	mockDB.GetDocumentReturns(database.Document{
 		ID:        "12345678",
@@ -108,6 +117,30 @@ func (s *CreateDocumentSuite) TestCreateMultipartDocument() {
 	require.Equal(s.T(), expectedResponse.Payload, body.Payload)
 }
 
+func (s *CreateDocumentSuite) TestCreateDocumentTokens() {
+	req, _ := http.NewRequest(http.MethodPost, "/api/",
+		bytes.NewReader([]byte(`{"content": "test"}`)),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	x, _ := io.ReadAll(rr.Result().Body)
+	var body struct {
+		Payload api.CreateResponse
+	}
+	json.Unmarshal(x, &body)
+
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+	require.NotEmpty(s.T(), body.Payload.DeleteToken)
+	require.NotEmpty(s.T(), body.Payload.EditToken)
+	require.NotEqual(s.T(), body.Payload.DeleteToken, body.Payload.EditToken)
+
+	// Only the tokens' hashes should be stored
+	_, document := s.db.CreateDocumentArgsForCall(0)
+	require.Equal(s.T(), util.HashToken(body.Payload.DeleteToken), document.DeleteTokenHash)
+	require.Equal(s.T(), util.HashToken(body.Payload.EditToken), document.EditTokenHash)
+}
+
 func (s *CreateDocumentSuite) TestStaticCreateDocument() {
 	// Setup multipart/form-data body
 	var b bytes.Buffer
@@ -126,6 +159,406 @@ func (s *CreateDocumentSuite) TestStaticCreateDocument() {
 	// add a test for content-type and body?
 }
 
+// TestStaticCreateDocumentTokens tests that documents created from a browser
+// show their tokens once, on the page they're redirected to
+func TestStaticCreateDocumentTokens(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := database.NewSQLite(&url.URL{Host: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	t.Cleanup(func() { sqlite.Close() })
+
+	srv := server.NewServer(&mockConfig, sqlite)
+	srv.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("content=test"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "text/html")
+	rr := executeRequest(req, srv)
+
+	require.Equal(t, http.StatusMovedPermanently, rr.Result().StatusCode)
+
+	location := rr.Result().Header.Get("Location")
+	cookies := rr.Result().Cookies()
+	require.Len(t, cookies, 1)
+	require.Equal(t, location, cookies[0].Path)
+	require.True(t, cookies[0].HttpOnly)
+
+	deleteToken, editToken, _ := strings.Cut(cookies[0].Value, ".")
+	document, err := sqlite.GetDocument(ctx, path.Base(location))
+	require.NoError(t, err)
+	require.True(t, util.CheckToken(deleteToken, document.DeleteTokenHash))
+	require.True(t, util.CheckToken(editToken, document.EditTokenHash))
+
+	req, _ = http.NewRequest(http.MethodGet, location, nil)
+	req.AddCookie(cookies[0])
+	rr = executeRequest(req, srv)
+
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	require.Contains(t, rr.Body.String(), deleteToken)
+	require.Contains(t, rr.Body.String(), editToken)
+	require.Equal(t, "no-store", rr.Result().Header.Get("Cache-Control"))
+	require.Len(t, rr.Result().Cookies(), 1)
+	require.Equal(t, -1, rr.Result().Cookies()[0].MaxAge)
+
+	// Tokens that aren't the document's own aren't shown
+	req, _ = http.NewRequest(http.MethodGet, location, nil)
+	req.AddCookie(&http.Cookie{Name: cookies[0].Name, Value: "forged.tokens"})
+	rr = executeRequest(req, srv)
+
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	require.NotContains(t, rr.Body.String(), "forged")
+	require.NotContains(t, rr.Body.String(), `class="tokens"`)
+
+	// Burn after reading documents aren't redirected to, so their tokens are shown straight away
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader("content=test&burn_after_read=true"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "text/html")
+	rr = executeRequest(req, srv)
+
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+	require.Contains(t, rr.Body.String(), `class="tokens"`)
+	require.Equal(t, "no-store", rr.Result().Header.Get("Cache-Control"))
+}
+
+func (s *CreateDocumentSuite) TestStaticCreateDocumentText() {
+	for _, method := range []string{http.MethodPost, http.MethodPut} {
+		s.SetupTest()
+
+		// Like cat main.go | curl --data-binary @- 'https://spaceb.in/?language=go'
+		req, _ := http.NewRequest(method, "https://spaceb.in/?language=go&expires_in=60", strings.NewReader("package main"))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Accept", "*/*")
+		rr := executeRequest(req, s.srv)
+
+		// Command line clients just get the document's URL, and its tokens in headers
+		require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode, method)
+		require.Equal(s.T(), "text/plain; charset=utf-8", rr.Result().Header.Get("Content-Type"))
+		require.Regexp(s.T(), `^http://spaceb\.in/[A-Za-z0-9]{8}\n$`, rr.Body.String())
+		require.NotEmpty(s.T(), rr.Result().Header.Get(api.DeleteTokenHeader))
+		require.NotEmpty(s.T(), rr.Result().Header.Get(api.EditTokenHeader))
+
+		_, document := s.db.CreateDocumentArgsForCall(0)
+		require.Equal(s.T(), "package main", document.Content)
+		require.Equal(s.T(), "Go", document.Language)
+		require.NotNil(s.T(), document.ExpiresAt)
+	}
+}
+
+func (s *CreateDocumentSuite) TestStaticCreateDocumentNegotiation() {
+	// Clients that ask for JSON get the API's response
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+	require.Equal(s.T(), "application/json", rr.Result().Header.Get("Content-Type"))
+
+	// Errors are plain text too
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader("a"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "*/*")
+	rr = executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, rr.Result().StatusCode)
+	require.Equal(s.T(), "text/plain; charset=utf-8", rr.Result().Header.Get("Content-Type"))
+
+	// Browsers are still sent to the document
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader("test"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	rr = executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusMovedPermanently, rr.Result().StatusCode)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentExpiry() {
+	tests := []struct {
+		name string
+		body string
+		want time.Duration
+	}{
+		{"Default", `{"content": "test"}`, 720 * time.Hour},
+		{"Requested", `{"content": "test", "expires_in": 3600}`, time.Hour},
+		{"Capped", `{"content": "test", "expires_in": 31536000}`, 720 * time.Hour},
+	}
+
+	for i, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "/api/", bytes.NewReader([]byte(tt.body)))
+		req.Header.Set("Content-Type", "application/json")
+		rr := executeRequest(req, s.srv)
+
+		require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode, tt.name)
+
+		_, document := s.db.CreateDocumentArgsForCall(i)
+		require.NotNil(s.T(), document.ExpiresAt, tt.name)
+		require.WithinDuration(s.T(), time.Now().Add(tt.want), *document.ExpiresAt, 2*time.Second, tt.name)
+	}
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentNegativeExpiry() {
+	req, _ := http.NewRequest(http.MethodPost, "/api/",
+		bytes.NewReader([]byte(`{"content": "test", "expires_in": -1}`)),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, rr.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.CreateDocumentCallCount())
+}
+
+func (s *CreateDocumentSuite) TestStaticCreateBurnAfterReadDocument() {
+	s.db.GetDocumentReturns(database.Document{
+		ID:            "12345678",
+		Content:       "test",
+		BurnAfterRead: true,
+	}, nil)
+
+	var b bytes.Buffer
+	mw := multipart.NewWriter(&b)
+	mw.WriteField("content", "test")
+	mw.WriteField("burn_after_read", "true")
+	mw.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "/", &b)
+	req.Header.Add("Content-Type", mw.FormDataContentType())
+	rr := executeRequest(req, s.srv)
+
+	// Redirecting to the document would burn it, so its link is shown instead
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+	require.Contains(s.T(), rr.Body.String(), `href="/12345678"`)
+
+	_, document := s.db.CreateDocumentArgsForCall(0)
+	require.True(s.T(), document.BurnAfterRead)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentLanguage() {
+	tests := []struct {
+		name string
+		body string
+		want string
+	}{
+		{"Detected", `{"content": "#!/bin/bash\necho test"}`, "Bash"},
+		{"Undetected", `{"content": "test"}`, "plaintext"},
+		{"Named", `{"content": "test", "language": "Go"}`, "Go"},
+		{"Alias", `{"content": "test", "language": "py"}`, "Python"},
+	}
+
+	for i, tt := range tests {
+		req, _ := http.NewRequest(http.MethodPost, "/api/", bytes.NewReader([]byte(tt.body)))
+		req.Header.Set("Content-Type", "application/json")
+		rr := executeRequest(req, s.srv)
+
+		require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode, tt.name)
+
+		_, document := s.db.CreateDocumentArgsForCall(i)
+		require.Equal(s.T(), tt.want, document.Language, tt.name)
+	}
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentUnknownLanguage() {
+	req, _ := http.NewRequest(http.MethodPost, "/api/",
+		bytes.NewReader([]byte(`{"content": "test", "language": "not-a-language"}`)),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, rr.Result().StatusCode)
+	require.Contains(s.T(), rr.Body.String(), "unknown language")
+	require.Equal(s.T(), 0, s.db.CreateDocumentCallCount())
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentFiles() {
+	req, _ := http.NewRequest(http.MethodPost, "/api/", bytes.NewReader([]byte(`{"files": [
+		{"name": "config.yml", "content": "debug: true"},
+		{"name": "app.log", "content": "started", "language": "plaintext"},
+		{"name": "fix.patch", "content": "--- a\n+++ b"}
+	]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+
+	// Each file keeps its name, and gets a language from it if it doesn't have one
+	_, document := s.db.CreateDocumentArgsForCall(0)
+	require.Equal(s.T(), []database.File{
+		{Name: "config.yml", Language: "YAML", ContentType: util.TextContentType, Content: "debug: true"},
+		{Name: "app.log", Language: "plaintext", ContentType: util.TextContentType, Content: "started"},
+		{Name: "fix.patch", Language: "Diff", ContentType: util.TextContentType, Content: "--- a\n+++ b"},
+	}, document.Files)
+
+	// The document itself is its first file
+	require.Equal(s.T(), "debug: true", document.Content)
+	require.Equal(s.T(), "YAML", document.Language)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentFile() {
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fw, _ := writer.CreateFormFile("file", "logo.png")
+	io.WriteString(fw, image)
+	writer.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, "/api/", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+
+	// Images are kept as they are, with no language to highlight them in
+	_, document := s.db.CreateDocumentArgsForCall(0)
+	require.Equal(s.T(), []database.File{
+		{Name: "logo.png", ContentType: "image/png", Content: image},
+	}, document.Files)
+	require.Equal(s.T(), "image/png", document.ContentType)
+	require.Equal(s.T(), "", document.Language)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentRaw() {
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("Hello, world!"))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+
+	_, document := s.db.CreateDocumentArgsForCall(0)
+	require.Equal(s.T(), "Hello, world!", document.Content)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentForm() {
+	// Forms are decoded, and their options taken from them
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader("content=Hello%2C+world%21&language=go"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, rr.Result().StatusCode)
+
+	_, document := s.db.CreateDocumentArgsForCall(0)
+	require.Equal(s.T(), "Hello, world!", document.Content)
+	require.Equal(s.T(), "Go", document.Language)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentTooLarge() {
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 400_001)))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusRequestEntityTooLarge, rr.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.CreateDocumentCallCount())
+}
+
+// TestCreateDocumentStreamed tests that raw bodies are streamed into a content store
+func TestCreateDocumentStreamed(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := database.NewSQLite(&url.URL{Host: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	t.Cleanup(func() { sqlite.Close() })
+
+	store, err := database.NewFileStore(t.TempDir())
+	require.NoError(t, err)
+
+	srv := server.NewServer(&mockConfig, database.NewBlobDatabase(sqlite, store))
+	srv.MountHandlers()
+
+	image := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 100_000)
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(image))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Accept", "text/plain")
+	rr := executeRequest(req, srv)
+
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	id := path.Base(strings.TrimSpace(rr.Body.String()))
+	document, err := database.NewBlobDatabase(sqlite, store).GetDocument(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, image, document.Content)
+	require.Equal(t, "image/png", document.ContentType)
+	require.Equal(t, []database.File{{
+		Name:        "file.png",
+		ContentType: "image/png",
+		Content:     image,
+		ContentHash: database.HashContent(image),
+		Size:        int64(len(image)),
+	}}, document.Files)
+
+	// Bodies are still cut off at the largest size allowed
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 400_001)))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "text/plain")
+	rr = executeRequest(req, srv)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Result().StatusCode)
+
+	count, err := sqlite.CountDocuments(ctx, database.DocumentFilter{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count.Documents)
+}
+
+// TestCreateDocumentUnstreamed tests raw uploads to a database without a content
+// store, which can't be streamed and are read whole instead
+func TestCreateDocumentUnstreamed(t *testing.T) {
+	ctx := context.Background()
+	sqlite, err := database.NewSQLite(&url.URL{Host: filepath.Join(t.TempDir(), "test.db")})
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(ctx))
+
+	t.Cleanup(func() { sqlite.Close() })
+
+	_, ok := database.Streamer(sqlite)
+	require.False(t, ok)
+
+	srv := server.NewServer(&mockConfig, sqlite)
+	srv.MountHandlers()
+
+	image := "\x89PNG\r\n\x1a\n" + strings.Repeat("\x00", 100_000)
+	req, _ := http.NewRequest(http.MethodPost, "/", strings.NewReader(image))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Accept", "text/plain")
+	rr := executeRequest(req, srv)
+
+	require.Equal(t, http.StatusOK, rr.Result().StatusCode)
+
+	id := path.Base(strings.TrimSpace(rr.Body.String()))
+	document, err := sqlite.GetDocument(ctx, id)
+	require.NoError(t, err)
+	require.Equal(t, image, document.Content)
+	require.Equal(t, "image/png", document.ContentType)
+	require.Len(t, document.Files, 1)
+	require.Equal(t, image, document.Files[0].Content)
+
+	req, _ = http.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 400_001)))
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("Accept", "text/plain")
+	rr = executeRequest(req, srv)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, rr.Result().StatusCode)
+
+	count, err := sqlite.CountDocuments(ctx, database.DocumentFilter{})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count.Documents)
+}
+
+func (s *CreateDocumentSuite) TestCreateDocumentDuplicateFiles() {
+	req, _ := http.NewRequest(http.MethodPost, "/api/", bytes.NewReader([]byte(`{"files": [
+		{"name": "app.log", "content": "started"},
+		{"name": "app.log", "content": "stopped"}
+	]}`)))
+	req.Header.Set("Content-Type", "application/json")
+	rr := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, rr.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.CreateDocumentCallCount())
+}
+
 // same as TestFetchNotFoundDocument; mocked GetDocument always returns a document, so this test needs to be reworked
 // func (s *CreateDocumentSuite) TestCreateBadDocument() {
 // 	req, _ := http.NewRequest(http.MethodPost, "/api/",
@@ -0,0 +1,76 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
+)
+
+func (s *Server) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "document")
+
+	// Validate document ID
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
+		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	document, err := lookupDocument(s, r.Context(), id)
+
+	if err != nil {
+		// If the document is not found (ErrNoRows), return the error with a 404
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		// Otherwise, return the error with a 500
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Only whoever created the document has its delete token. Documents without
+	// one, like custom documents, can't be deleted at all.
+	if !util.CheckToken(r.Header.Get(api.DeleteTokenHeader), document.DeleteTokenHash) {
+		util.WriteError(w, http.StatusForbidden, errors.New("invalid delete token"))
+		return
+	}
+
+	if err := s.Database.DeleteDocument(r.Context(), id); err != nil {
+		// The document may have been deleted by another request in the meantime
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := util.WriteJSON(w, http.StatusOK, map[string]string{"id": id}); err != nil {
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DeleteDocumentSuite struct {
+	suite.Suite
+
+	srv *server.Server
+	db  *databasefakes.FakeDatabase
+}
+
+func (s *DeleteDocumentSuite) SetupTest() {
+	s.db = &databasefakes.FakeDatabase{}
+
+	s.db.GetDocumentReturns(database.Document{
+		ID:              "12345678",
+		Content:         "test",
+		DeleteTokenHash: util.HashToken("token"),
+	}, nil)
+
+	s.srv = server.NewServer(&mockConfig, s.db)
+	s.srv.MountHandlers()
+}
+
+func (s *DeleteDocumentSuite) TestDeleteDocument() {
+	req, _ := http.NewRequest(http.MethodDelete, "/api/12345678", nil)
+	req.Header.Set(api.DeleteTokenHeader, "token")
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), 1, s.db.DeleteDocumentCallCount())
+
+	_, id := s.db.DeleteDocumentArgsForCall(0)
+	require.Equal(s.T(), "12345678", id)
+}
+
+func (s *DeleteDocumentSuite) TestDeleteDocumentWrongToken() {
+	for _, token := range []string{"", "wrong"} {
+		req, _ := http.NewRequest(http.MethodDelete, "/api/12345678", nil)
+		req.Header.Set(api.DeleteTokenHeader, token)
+		res := executeRequest(req, s.srv)
+
+		require.Equal(s.T(), http.StatusForbidden, res.Result().StatusCode)
+	}
+
+	require.Equal(s.T(), 0, s.db.DeleteDocumentCallCount())
+}
+
+// TestDeleteDocumentWithoutToken tests that documents without a delete token,
+// like custom documents, can't be deleted
+func (s *DeleteDocumentSuite) TestDeleteDocumentWithoutToken() {
+	s.db.GetDocumentReturns(database.Document{ID: "12345678", Content: "test"}, nil)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/12345678", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusForbidden, res.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.DeleteDocumentCallCount())
+}
+
+func (s *DeleteDocumentSuite) TestDeleteMissingDocument() {
+	s.db.GetDocumentReturns(database.Document{}, sql.ErrNoRows)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/api/12345678", nil)
+	req.Header.Set(api.DeleteTokenHeader, "token")
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.DeleteDocumentCallCount())
+}
+
+func (s *DeleteDocumentSuite) TestDeleteBadIDDocument() {
+	req, _ := http.NewRequest(http.MethodDelete, "/api/1234", nil)
+	req.Header.Set(api.DeleteTokenHeader, "token")
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, res.Result().StatusCode)
+
+	x, _ := io.ReadAll(res.Result().Body)
+	var body DocumentResponse
+	json.Unmarshal(x, &body)
+
+	require.Equal(s.T(), "id is of length 4, should be 8", body.Error)
+}
+
+func TestDeleteDocumentSuite(t *testing.T) {
+	suite.Run(t, new(DeleteDocumentSuite))
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
+)
+
+// diffSource retrieves the content of one side of a diff. ref is a document ID,
+// optionally followed by @ and a revision number, like "abcdefgh@2".
+func diffSource(s *Server, ctx context.Context, ref string) (string, error) {
+	id, rev, hasRevision := strings.Cut(ref, "@")
+
+	// Validate document ID
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
+		return "", fmt.Errorf("bad request: id is of length %d, should be %d", len(id), s.Config.IDLength)
+	}
+
+	document, err := peekDocument(s, ctx, id)
+
+	if err != nil {
+		return "", err
+	}
+
+	if !hasRevision {
+		return document.Content, nil
+	}
+
+	n, err := strconv.Atoi(rev)
+
+	if err != nil || n < 1 {
+		return "", fmt.Errorf("bad request: invalid revision %q", rev)
+	}
+
+	revision, err := s.Database.GetRevision(ctx, id, n)
+
+	if err != nil {
+		return "", err
+	}
+
+	return revision.Content, nil
+}
+
+// diffDocuments diffs the documents named in the request's URL, returning the
+// names of both sides and the hunks that differ between them.
+func diffDocuments(s *Server, r *http.Request) (string, string, []util.DiffHunk, error) {
+	from, to := chi.URLParam(r, "document"), chi.URLParam(r, "other")
+	a, err := diffSource(s, r.Context(), from)
+
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	b, err := diffSource(s, r.Context(), to)
+
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	hunks, err := util.Diff(a, b)
+
+	if err != nil {
+		return "", "", nil, err
+	}
+
+	return from, to, hunks, nil
+}
+
+// apiHunks converts a diff's hunks to how the API returns them.
+func apiHunks(hunks []util.DiffHunk) []api.DiffHunk {
+	converted := make([]api.DiffHunk, 0, len(hunks))
+
+	for _, hunk := range hunks {
+		converted = append(converted, api.DiffHunk{
+			OldStart: hunk.OldStart,
+			OldLines: hunk.OldLines,
+			NewStart: hunk.NewStart,
+			NewLines: hunk.NewLines,
+			Lines:    hunk.Lines,
+		})
+	}
+
+	return converted
+}
+
+// diffStatus picks the HTTP status code for an error returned by diffDocuments.
+func diffStatus(err error) int {
+	switch {
+	case strings.Contains(err.Error(), "bad request:"):
+		return http.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows):
+		return http.StatusNotFound
+	case errors.Is(err, errBurnAfterRead):
+		return http.StatusForbidden
+	case errors.Is(err, util.ErrDiffTooLarge):
+		return http.StatusUnprocessableEntity
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (s *Server) StaticDiff(w http.ResponseWriter, r *http.Request) {
+	theme, err := s.theme(r)
+
+	if err != nil {
+		util.RenderError(&resources, w, http.StatusBadRequest, err)
+		return
+	}
+
+	from, to, hunks, err := diffDocuments(s, r)
+
+	if err != nil {
+		util.RenderError(&resources, w, diffStatus(err), err)
+		return
+	}
+
+	t, err := template.ParseFS(resources, "web/document.html")
+
+	if err != nil {
+		util.RenderError(&resources, w, http.StatusInternalServerError, err)
+		return
+	}
+
+	content := util.UnifiedDiff(from, to, hunks)
+
+	// Like diff -s, say so when there's nothing to show
+	if len(hunks) == 0 {
+		content = fmt.Sprintf("%s and %s are identical\n", from, to)
+	}
+
+	highlighted, err := util.Highlight(content, "diff", theme, util.HighlightOptions{})
+
+	if err != nil {
+		util.RenderError(&resources, w, http.StatusInternalServerError, err)
+		return
+	}
+
+	data := map[string]interface{}{
+		"Theme":       theme,
+		"Themes":      util.Themes(),
+		"Scheme":      util.ThemeScheme(theme),
+		"Content":     content,
+		"Highlighted": template.HTML(highlighted),
+		"Extension":   "diff",
+		"Analytics":   template.HTML(config.Config.Analytics),
+	}
+
+	if err := t.Execute(w, data); err != nil {
+		util.RenderError(&resources, w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+func (s *Server) FetchDiff(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+
+	if format != "" && format != "text" && format != "json" && format != "html" {
+		util.WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown format %q, should be text, json or html", format))
+		return
+	}
+
+	from, to, hunks, err := diffDocuments(s, r)
+
+	if err != nil {
+		util.WriteError(w, diffStatus(err), err)
+		return
+	}
+
+	switch format {
+	case "json":
+		if err := util.WriteJSON(w, http.StatusOK, api.DiffResponse{
+			From:  from,
+			To:    to,
+			Hunks: apiHunks(hunks),
+		}); err != nil {
+			util.WriteError(w, http.StatusInternalServerError, err)
+		}
+	case "html":
+		// A highlighted fragment, with its stylesheet, for embedding in other pages
+		theme, err := s.theme(r)
+
+		if err != nil {
+			util.WriteError(w, http.StatusBadRequest, err)
+			return
+		}
+
+		highlighted, err := util.Highlight(util.UnifiedDiff(from, to, hunks), "diff", theme, util.HighlightOptions{})
+
+		if err != nil {
+			util.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		css, err := util.HighlightCSS(theme)
+
+		if err != nil {
+			util.WriteError(w, http.StatusInternalServerError, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("<style>" + css + "</style>" + highlighted))
+	default:
+		// Respond with only the unified diff, which is empty if there are no changes
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(util.UnifiedDiff(from, to, hunks)))
+	}
+}
@@ -0,0 +1,167 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type DiffSuite struct {
+	suite.Suite
+
+	srv *server.Server
+	db  *databasefakes.FakeDatabase
+}
+
+func (s *DiffSuite) SetupTest() {
+	s.db = &databasefakes.FakeDatabase{}
+
+	documents := map[string]database.Document{
+		"aaaaaaaa": {ID: "aaaaaaaa", Content: "port=80\nhost=localhost\n"},
+		"bbbbbbbb": {ID: "bbbbbbbb", Content: "port=8080\nhost=localhost\n"},
+		"burnburn": {ID: "burnburn", Content: "secret\n", BurnAfterRead: true},
+		"longlong": {ID: "longlong", Content: strings.Repeat("line\n", util.MaxDiffLines)},
+	}
+
+	s.db.GetDocumentCalls(func(ctx context.Context, id string) (database.Document, error) {
+		document, ok := documents[id]
+
+		if !ok {
+			return database.Document{}, sql.ErrNoRows
+		}
+
+		return document, nil
+	})
+	s.db.GetRevisionReturns(database.Revision{DocumentID: "aaaaaaaa", Revision: 1, Content: "port=80\n"}, nil)
+
+	s.srv = server.NewServer(&mockConfig, s.db)
+	s.srv.MountHandlers()
+}
+
+func (s *DiffSuite) TestFetchDiff() {
+	req, _ := http.NewRequest(http.MethodGet, "/api/aaaaaaaa/diff/bbbbbbbb", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "text/plain", res.Result().Header.Get("Content-Type"))
+
+	x, _ := io.ReadAll(res.Result().Body)
+	require.Equal(s.T(), "--- aaaaaaaa\n+++ bbbbbbbb\n@@ -1,2 +1,2 @@\n-port=80\n+port=8080\n host=localhost\n",
+		string(x))
+}
+
+func (s *DiffSuite) TestFetchDiffJSON() {
+	req, _ := http.NewRequest(http.MethodGet, "/api/aaaaaaaa/diff/bbbbbbbb?format=json", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+
+	x, _ := io.ReadAll(res.Result().Body)
+	var body struct {
+		Payload api.DiffResponse
+	}
+	json.Unmarshal(x, &body)
+
+	require.Equal(s.T(), "aaaaaaaa", body.Payload.From)
+	require.Equal(s.T(), "bbbbbbbb", body.Payload.To)
+	require.Len(s.T(), body.Payload.Hunks, 1)
+	require.Equal(s.T(), []string{"-port=80", "+port=8080", " host=localhost"}, body.Payload.Hunks[0].Lines)
+}
+
+func (s *DiffSuite) TestFetchDiffHTML() {
+	req, _ := http.NewRequest(http.MethodGet, "/api/aaaaaaaa/diff/bbbbbbbb?format=html", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "text/html; charset=utf-8", res.Result().Header.Get("Content-Type"))
+
+	x, _ := io.ReadAll(res.Result().Body)
+	require.True(s.T(), strings.HasPrefix(string(x), "<style>"))
+	require.Contains(s.T(), string(x), "port=8080")
+}
+
+func (s *DiffSuite) TestFetchDiffRevision() {
+	req, _ := http.NewRequest(http.MethodGet, "/api/aaaaaaaa@1/diff/aaaaaaaa", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+
+	_, id, n := s.db.GetRevisionArgsForCall(0)
+	require.Equal(s.T(), "aaaaaaaa", id)
+	require.Equal(s.T(), 1, n)
+
+	x, _ := io.ReadAll(res.Result().Body)
+	require.Contains(s.T(), string(x), "+host=localhost\n")
+}
+
+func (s *DiffSuite) TestFetchDiffErrors() {
+	tests := []struct {
+		path   string
+		status int
+	}{
+		{"/api/aaaaaaaa/diff/cccccccc", http.StatusNotFound},
+		{"/api/cccccccc/diff/bbbbbbbb", http.StatusNotFound},
+		{"/api/aaaa/diff/bbbbbbbb", http.StatusBadRequest},
+		{"/api/aaaaaaaa@0/diff/bbbbbbbb", http.StatusBadRequest},
+		{"/api/aaaaaaaa/diff/bbbbbbbb?format=yaml", http.StatusBadRequest},
+		{"/api/aaaaaaaa/diff/burnburn", http.StatusForbidden},
+		{"/api/aaaaaaaa/diff/longlong", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+		res := executeRequest(req, s.srv)
+
+		require.Equal(s.T(), tt.status, res.Result().StatusCode, tt.path)
+	}
+}
+
+func (s *DiffSuite) TestStaticDiff() {
+	req, _ := http.NewRequest(http.MethodGet, "/aaaaaaaa/diff/bbbbbbbb", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+
+	x, _ := io.ReadAll(res.Result().Body)
+	require.Contains(s.T(), string(x), "port=8080")
+	require.Contains(s.T(), string(x), `class="gi"`) // Highlighted as an inserted line
+}
+
+func (s *DiffSuite) TestStaticDiffMissingDocument() {
+	req, _ := http.NewRequest(http.MethodGet, "/aaaaaaaa/diff/cccccccc", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+}
+
+func TestDiffSuite(t *testing.T) {
+	suite.Run(t, new(DiffSuite))
+}
@@ -22,18 +22,121 @@ import (
 	"errors"
 	"fmt"
 	"html/template"
+	"mime"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/lukewhrit/spacebin/internal/config"
 	"github.com/lukewhrit/spacebin/internal/database"
 	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 	"golang.org/x/exp/slices"
 )
 
+// isCustomDocument reports whether id is one of the custom documents listed in
+// SPIRIT_DOCUMENTS, which are exempt from the ID length check.
+func isCustomDocument(documents []string, id string) bool {
+	return slices.ContainsFunc(documents, func(entry string) bool {
+		name, _, _ := strings.Cut(entry, "=")
+		return strings.TrimSpace(name) == id
+	})
+}
+
+// lookupDocument retrieves a document without reading it, so burn after reading
+// documents are left in place.
+func lookupDocument(s *Server, ctx context.Context, id string) (database.Document, error) {
+	document, err := s.Database.GetDocument(ctx, id)
+
+	if err != nil {
+		return document, err
+	}
+
+	// Expired documents may still be in the database until the reaper next runs,
+	// so treat them as if they were already gone. Like the reaper, documents
+	// without an expiry time expire once they're older than the maximum age,
+	// except for custom documents, which never expire. Documents without a
+	// creation time can't be compared, so they're kept, as the reaper keeps them.
+	now := time.Now()
+	age := time.Duration(s.Config.ExpirationAge) * time.Hour
+
+	if document.ExpiresAt != nil && !now.Before(*document.ExpiresAt) {
+		return database.Document{}, sql.ErrNoRows
+	}
+
+	if document.ExpiresAt == nil && age > 0 && !document.CreatedAt.IsZero() &&
+		document.CreatedAt.Before(now.Add(-age)) && !isCustomDocument(s.Config.Documents, id) {
+		return database.Document{}, sql.ErrNoRows
+	}
+
+	return document, nil
+}
+
+// errBurnAfterRead is returned when a burn after reading document would be read
+// other than by fetching it, which would let it be seen without being burned.
+var errBurnAfterRead = errors.New("burn after reading documents can only be viewed directly")
+
+// peekDocument retrieves a document to be shown somewhere other than its own
+// page, like in its revisions or a diff. Burn after reading documents are refused.
+func peekDocument(s *Server, ctx context.Context, id string) (database.Document, error) {
+	document, err := lookupDocument(s, ctx, id)
+
+	if err != nil {
+		return document, err
+	}
+
+	if document.BurnAfterRead {
+		return database.Document{}, errBurnAfterRead
+	}
+
+	return document, nil
+}
+
 func getDocument(s *Server, ctx context.Context, id string) (database.Document, error) {
-	return s.Database.GetDocument(ctx, id)
+	document, err := lookupDocument(s, ctx, id)
+
+	if err != nil {
+		return document, err
+	}
+
+	// Burn after reading documents are deleted as they're read. If another request
+	// beats us to it, the document is gone and ErrNoRows is returned.
+	if document.BurnAfterRead {
+		return s.Database.GetAndDeleteDocument(ctx, id)
+	}
+
+	return document, nil
+}
+
+// apiDocument converts a document to how the API returns it.
+func apiDocument(document database.Document) api.Document {
+	var files []api.File
+
+	for _, file := range document.Files {
+		files = append(files, api.File{
+			Name:        file.Name,
+			Language:    file.Language,
+			ContentType: file.ContentType,
+			Content:     file.Content,
+			ContentHash: file.ContentHash,
+			Size:        file.Size,
+		})
+	}
+
+	return api.Document{
+		ID:            document.ID,
+		Content:       document.Content,
+		CreatedAt:     document.CreatedAt,
+		UpdatedAt:     document.UpdatedAt,
+		ExpiresAt:     document.ExpiresAt,
+		BurnAfterRead: document.BurnAfterRead,
+		ContentHash:   document.ContentHash,
+		Language:      document.Language,
+		ContentType:   document.ContentType,
+		Size:          document.Size,
+		Files:         files,
+	}
 }
 
 func (s *Server) StaticDocument(w http.ResponseWriter, r *http.Request) {
@@ -41,12 +144,27 @@ func (s *Server) StaticDocument(w http.ResponseWriter, r *http.Request) {
 	id := params[0]
 
 	// Validate document ID
-	if len(id) != s.Config.IDLength && !slices.Contains(s.Config.Documents, id) {
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
 		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
 		util.RenderError(&resources, w, http.StatusBadRequest, err)
 		return
 	}
 
+	// Check the theme and lines first, as reading burns documents that are burned after reading
+	theme, err := s.theme(r)
+
+	if err != nil {
+		util.RenderError(&resources, w, http.StatusBadRequest, err)
+		return
+	}
+
+	lines, err := lineRanges(r, "hl")
+
+	if err != nil {
+		util.RenderError(&resources, w, http.StatusBadRequest, err)
+		return
+	}
+
 	// Retrieve document from the database
 	document, err := getDocument(s, r.Context(), id)
 
@@ -69,25 +187,55 @@ func (s *Server) StaticDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	extension := ""
+	// An extension in the URL overrides the language the document was saved with
+	extension, language := "", document.Language
 
 	if len(params) == 2 {
-		extension = params[1]
+		extension, language = params[1], params[1]
 	}
 
-	highlighted, css, err := util.Highlight(document.Content, extension)
+	// Only text can be copied
+	content := document.Content
 
-	if err != nil {
-		util.RenderError(&resources, w, http.StatusInternalServerError, err)
-		return
+	if !util.IsText(document.ContentType) {
+		content = ""
 	}
 
 	data := map[string]interface{}{
-		"Stylesheet":  template.CSS(css),
-		"Content":     document.Content,
-		"Highlighted": template.HTML(highlighted),
-		"Extension":   extension,
-		"Analytics":   template.HTML(config.Config.Analytics),
+		"ID":        document.ID,
+		"Theme":     theme,
+		"Themes":    util.Themes(),
+		"Scheme":    util.ThemeScheme(theme),
+		"Content":   content,
+		"Extension": extension,
+		"Analytics": template.HTML(config.Config.Analytics),
+	}
+
+	// Whoever created the document from their browser is shown its tokens, once
+	if tokens, ok := takeTokens(w, r, document); ok {
+		w.Header().Set("Cache-Control", "no-store")
+		data["DeleteToken"], data["EditToken"] = tokens.delete, tokens.edit
+	}
+
+	// Documents with several files show each in its own section, in its own language
+	if len(document.Files) > 0 {
+		files, err := s.highlightFiles(document, theme)
+
+		if err != nil {
+			util.RenderError(&resources, w, http.StatusInternalServerError, err)
+			return
+		}
+
+		data["Files"] = files
+	} else {
+		highlighted, err := s.highlightDocument(document, language, theme, lines)
+
+		if err != nil {
+			util.RenderError(&resources, w, http.StatusInternalServerError, err)
+			return
+		}
+
+		data["Highlighted"] = template.HTML(highlighted)
 	}
 
 	if err := t.Execute(w, data); err != nil {
@@ -100,7 +248,7 @@ func (s *Server) FetchDocument(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "document")
 
 	// Validate document ID
-	if len(id) != s.Config.IDLength && !slices.Contains(s.Config.Documents, id) {
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
 		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
 		util.WriteError(w, http.StatusBadRequest, err)
 		return
@@ -121,7 +269,7 @@ func (s *Server) FetchDocument(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Try responding with the document and a 200, or write an error if that fails
-	if err := util.WriteJSON(w, http.StatusOK, document); err != nil {
+	if err := util.WriteJSON(w, http.StatusOK, apiDocument(document)); err != nil {
 		util.WriteError(w, http.StatusInternalServerError, err)
 		return
 	}
@@ -131,31 +279,135 @@ func (s *Server) FetchRawDocument(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "document")
 
 	// Validate document ID
-	if len(id) != s.Config.IDLength && !slices.Contains(s.Config.Documents, id) {
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
 		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
 		util.WriteError(w, http.StatusBadRequest, err)
 		return
 	}
 
-	document, err := getDocument(s, r.Context(), id)
+	lines, err := lineRanges(r, "lines")
+
+	if err != nil {
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	// Look the document up without reading it, so that burn after reading documents
+	// are only burned once the file and lines asked for are known to be there
+	document, err := lookupDocument(s, r.Context(), id)
 
 	w.Header().Set("Content-Type", "text/plain")
 
 	if err != nil {
-		// If the document is not found (ErrNoRows), return the error with a 404
-		if errors.Is(err, sql.ErrNoRows) {
-			w.WriteHeader(http.StatusNotFound)
-			w.Write([]byte(fmt.Sprintf("Document with ID %s not found: %s", id, err.Error())))
+		writeRawError(w, id, err)
+		return
+	}
+
+	filename := chi.URLParam(r, "filename")
+	content, contentType, name, ok := rawContent(document, filename)
+
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("Document with ID %s has no file named %s", id, filename)))
+		return
+	}
+
+	if lines != nil && !util.IsText(contentType) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("Document with ID %s isn't text, so has no lines", id)))
+		return
+	}
+
+	// Burn after reading documents are deleted as they're read. If another request
+	// beats us to it, the document is gone and ErrNoRows is returned.
+	if document.BurnAfterRead {
+		document, err = s.Database.GetAndDeleteDocument(r.Context(), id)
+
+		if err != nil {
+			writeRawError(w, id, err)
 			return
 		}
 
-		// Otherwise, return the error with a 500
-		w.WriteHeader(http.StatusInternalServerError)
-		w.Write([]byte(fmt.Sprintf("Error fetching document with ID %s: %s", id, err.Error())))
-		return
+		content, contentType, name, _ = rawContent(document, filename)
+	}
+
+	// Respond with only the lines asked for
+	if lines != nil {
+		content = util.SliceLines(content, lines)
+	}
+
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
+	}
+
+	// Files are downloaded under their own names. Text and images are shown in the
+	// browser, but anything else is only ever saved.
+	if name != "" {
+		disposition := "attachment"
+
+		if util.IsText(contentType) || util.IsImage(contentType) {
+			disposition = "inline"
+		}
+
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": name}))
 	}
 
-	// Respond with only the documents content
 	w.WriteHeader(http.StatusOK)
-	w.Write([]byte(document.Content))
+	w.Write([]byte(content))
+}
+
+// rawContent picks the content of a document to respond with, along with its
+// content type and the name it's downloaded under. If filename is set, it's that
+// of the named file, and ok is false if the document has no such file.
+func rawContent(document database.Document, filename string) (content, contentType, name string, ok bool) {
+	content, contentType = document.Content, document.ContentType
+
+	// Documents of files are named after their first
+	if len(document.Files) > 0 {
+		name = document.Files[0].Name
+	}
+
+	if filename == "" {
+		return content, contentType, name, true
+	}
+
+	i := slices.IndexFunc(document.Files, func(file database.File) bool { return file.Name == filename })
+
+	if i < 0 {
+		return "", "", "", false
+	}
+
+	return document.Files[i].Content, document.Files[i].ContentType, filename, true
+}
+
+// writeRawError responds with an error fetching a raw document, as plain text.
+func writeRawError(w http.ResponseWriter, id string, err error) {
+	// If the document is not found (ErrNoRows), return the error with a 404
+	if errors.Is(err, sql.ErrNoRows) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("Document with ID %s not found: %s", id, err.Error())))
+		return
+	}
+
+	// Otherwise, return the error with a 500
+	w.WriteHeader(http.StatusInternalServerError)
+	w.Write([]byte(fmt.Sprintf("Error fetching document with ID %s: %s", id, err.Error())))
+}
+
+// lineRanges parses the line ranges in a query parameter, returning nil if it
+// isn't set.
+func lineRanges(r *http.Request, param string) ([][2]int, error) {
+	value := r.URL.Query().Get(param)
+
+	if value == "" {
+		return nil, nil
+	}
+
+	lines, err := util.ParseLineRanges(value)
+
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", param, err)
+	}
+
+	return lines, nil
 }
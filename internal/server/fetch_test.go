@@ -17,6 +17,7 @@
 package server_test
 
 import (
+	"database/sql"
 	"encoding/json"
 	"io"
 	"net/http"
@@ -39,16 +40,21 @@ type FetchDocumentSuite struct {
 	suite.Suite
 
 	srv *server.Server
+	now time.Time
 }
 
 func (s *FetchDocumentSuite) SetupTest() {
 	mockDB := &databasefakes.FakeDatabase{}
 
+	// Documents older than the expiration age are treated as missing, so the
+	// fixture has to be recent
+	s.now = time.Now().UTC().Truncate(time.Second)
+
 	mockDB.GetDocumentReturns(database.Document{
 		ID:        "12345678",
 		Content:   "test",
-		CreatedAt: time.Date(1970, 1, 1, 1, 1, 1, 1, time.UTC),
-		UpdatedAt: time.Date(1970, 1, 1, 1, 1, 1, 1, time.UTC),
+		CreatedAt: s.now,
+		UpdatedAt: s.now,
 	}, nil)
 
 	s.srv = server.NewServer(&mockConfig, mockDB)
@@ -69,8 +75,8 @@ func (s *FetchDocumentSuite) TestFetchDocument() {
 		Payload: database.Document{
 			ID:        "12345678",
 			Content:   "test",
-			CreatedAt: time.Date(1970, 1, 1, 1, 1, 1, 1, time.UTC),
-			UpdatedAt: time.Date(1970, 1, 1, 1, 1, 1, 1, time.UTC),
+			CreatedAt: s.now,
+			UpdatedAt: s.now,
 		},
 	}
 
@@ -86,6 +92,95 @@ func (s *FetchDocumentSuite) TestFetchRawDocument() {
 	require.Equal(s.T(), "test", res.Body.String())
 }
 
+func (s *FetchDocumentSuite) TestFetchRawDocumentLines() {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "one\ntwo\nthree\nfour\n"}, nil)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/12345678/raw?lines=2-3", nil)
+	res := executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "two\nthree\n", res.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678/raw?lines=3-2", nil)
+	res = executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, res.Result().StatusCode)
+	require.Equal(s.T(), 1, mockDB.GetDocumentCallCount())
+}
+
+func (s *FetchDocumentSuite) TestFetchDocumentFiles() {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "debug: true", Files: []database.File{
+		{Name: "config.yml", Language: "YAML", Content: "debug: true"},
+		{Name: "app log.txt", Language: "plaintext", Content: "started\nstopped\n"},
+	}}, nil)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678", nil)
+	res := executeRequest(req, srv)
+
+	var body DocumentResponse
+	json.Unmarshal(res.Body.Bytes(), &body)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Len(s.T(), body.Payload.Files, 2)
+	require.Equal(s.T(), "app log.txt", body.Payload.Files[1].Name)
+
+	// Each file can be fetched on its own
+	req, _ = http.NewRequest(http.MethodGet, "/12345678/raw/app%20log.txt?lines=2", nil)
+	res = executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "stopped\n", res.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/12345678/raw/missing.txt", nil)
+	res = executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+}
+
+func (s *FetchDocumentSuite) TestFetchRawDocumentBinary() {
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: image, ContentType: "image/png",
+		Files: []database.File{
+			{Name: "logo.png", ContentType: "image/png", Content: image},
+			{Name: "report.pdf", ContentType: "application/pdf", Content: "%PDF-1.7\n"},
+		}}, nil)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	// Images are served as they were uploaded, to be shown in the browser
+	req, _ := http.NewRequest(http.MethodGet, "/12345678/raw", nil)
+	res := executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "image/png", res.Result().Header.Get("Content-Type"))
+	require.Equal(s.T(), `inline; filename=logo.png`, res.Result().Header.Get("Content-Disposition"))
+	require.Equal(s.T(), image, res.Body.String())
+
+	// Anything else is downloaded
+	req, _ = http.NewRequest(http.MethodGet, "/12345678/raw/report.pdf", nil)
+	res = executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "application/pdf", res.Result().Header.Get("Content-Type"))
+	require.Equal(s.T(), `attachment; filename=report.pdf`, res.Result().Header.Get("Content-Disposition"))
+
+	// And has no lines to pick
+	req, _ = http.NewRequest(http.MethodGet, "/12345678/raw/report.pdf?lines=1", nil)
+	res = executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, res.Result().StatusCode)
+}
+
 // mocked GetDocument always returns a document, so this test needs to be reworked
 // func (s *FetchDocumentSuite) TestFetchNotFoundDocument() {
 // 	req, _ := http.NewRequest(http.MethodGet, "/api/12345679", nil)
@@ -101,6 +196,135 @@ func (s *FetchDocumentSuite) TestFetchRawDocument() {
 // 	require.Equal(s.T(), "sql: no rows in result set", body.Error)
 // }
 
+func (s *FetchDocumentSuite) TestFetchExpiredDocument() {
+	mockDB := &databasefakes.FakeDatabase{}
+	expiresAt := time.Now().Add(-time.Minute)
+
+	mockDB.GetDocumentReturns(database.Document{
+		ID:        "12345678",
+		Content:   "test",
+		CreatedAt: time.Date(1970, 1, 1, 1, 1, 1, 1, time.UTC),
+		UpdatedAt: time.Date(1970, 1, 1, 1, 1, 1, 1, time.UTC),
+		ExpiresAt: &expiresAt,
+	}, nil)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	// Expired documents that haven't been deleted yet should not be served
+	for _, path := range []string{"/api/12345678", "/api/12345678/raw", "/12345678"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		res := executeRequest(req, srv)
+
+		require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode, path)
+	}
+}
+
+// TestFetchDocumentWithoutExpiry tests that documents stored without an expiry
+// time expire once they're older than the expiration age, as the reaper deletes
+// them, unless they're custom documents
+func (s *FetchDocumentSuite) TestFetchDocumentWithoutExpiry() {
+	mockDB := &databasefakes.FakeDatabase{}
+	old := time.Now().Add(-721 * time.Hour)
+
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "test", CreatedAt: old}, nil)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	for _, path := range []string{"/api/12345678", "/api/12345678/raw", "/12345678"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		res := executeRequest(req, srv)
+
+		require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode, path)
+	}
+
+	config := mockConfig
+	config.Documents = []string{"about=about.md"}
+	mockDB.GetDocumentReturns(database.Document{ID: "about", Content: "test", CreatedAt: old}, nil)
+
+	srv = server.NewServer(&config, mockDB)
+	srv.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/about", nil)
+	res := executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+}
+
+func (s *FetchDocumentSuite) TestFetchBurnAfterReadDocument() {
+	mockDB := &databasefakes.FakeDatabase{}
+	document := database.Document{
+		ID:            "12345678",
+		Content:       "secret",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+		BurnAfterRead: true,
+	}
+
+	// Only the first reader gets the document, any others find it already deleted
+	mockDB.GetDocumentReturns(document, nil)
+	mockDB.GetAndDeleteDocumentReturnsOnCall(0, document, nil)
+	mockDB.GetAndDeleteDocumentReturnsOnCall(1, database.Document{}, sql.ErrNoRows)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678/raw", nil)
+	res := executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "secret", res.Body.String())
+
+	req, _ = http.NewRequest(http.MethodGet, "/api/12345678/raw", nil)
+	res = executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+	require.Equal(s.T(), 2, mockDB.GetAndDeleteDocumentCallCount())
+}
+
+// TestFetchBurnAfterReadDocumentRefused tests that raw requests which are refused
+// don't burn the document they were for
+func (s *FetchDocumentSuite) TestFetchBurnAfterReadDocumentRefused() {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{
+		ID:            "12345678",
+		BurnAfterRead: true,
+		Files: []database.File{
+			{Name: "image.png", Content: "\x89PNG", ContentType: "image/png"},
+		},
+	}, nil)
+
+	srv := server.NewServer(&mockConfig, mockDB)
+	srv.MountHandlers()
+
+	for path, code := range map[string]int{
+		"/api/12345678/raw/missing.txt":       http.StatusNotFound,
+		"/api/12345678/raw/image.png?lines=1": http.StatusBadRequest,
+	} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		res := executeRequest(req, srv)
+
+		require.Equal(s.T(), code, res.Result().StatusCode, path)
+	}
+
+	require.Equal(s.T(), 0, mockDB.GetAndDeleteDocumentCallCount())
+}
+
+func (s *FetchDocumentSuite) TestFetchCustomDocument() {
+	config := mockConfig
+	config.Documents = []string{"about=./docs/about.md"}
+
+	srv := server.NewServer(&config, &databasefakes.FakeDatabase{})
+	srv.MountHandlers()
+
+	// Custom documents don't need to match the configured ID length
+	req, _ := http.NewRequest(http.MethodGet, "/api/about", nil)
+	res := executeRequest(req, srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+}
+
 // TestFetchBadIDDocument tests fetching a document with an invalid ID
 func (s *FetchDocumentSuite) TestFetchBadIDDocument() {
 	req, _ := http.NewRequest(http.MethodGet, "/api/1234", nil)
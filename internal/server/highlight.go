@@ -0,0 +1,235 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/util"
+)
+
+// highlightKey identifies a highlighted document, or one of its files. Marked
+// lines aren't part of it, since they're marked after the cached HTML is found.
+type highlightKey struct {
+	id       string
+	file     string
+	language string
+	theme    string
+}
+
+// highlightedDocument is a document's highlighted HTML, and the hash of the
+// content it was made from.
+type highlightedDocument struct {
+	hash string
+	html string
+}
+
+// highlightCache keeps the most recently used highlighted documents, up to a
+// number of documents and a number of bytes of HTML between them.
+type highlightCache struct {
+	mu       sync.Mutex // Held while adding, so the byte count matches what's kept
+	entries  *lru.Cache[highlightKey, highlightedDocument]
+	maxBytes int64
+	bytes    int64
+}
+
+// newHighlightCache creates a highlightCache holding at most maxEntries
+// documents, with at most maxBytes of HTML between them, if maxBytes isn't zero.
+func newHighlightCache(maxEntries int, maxBytes int64) *highlightCache {
+	c := &highlightCache{maxBytes: maxBytes}
+
+	// Only called while adding, with the lock held
+	c.entries, _ = lru.NewWithEvict(maxEntries, func(_ highlightKey, h highlightedDocument) {
+		c.bytes -= int64(len(h.html))
+	})
+
+	return c
+}
+
+func (c *highlightCache) Get(key highlightKey) (highlightedDocument, bool) {
+	return c.entries.Get(key)
+}
+
+func (c *highlightCache) Add(key highlightKey, h highlightedDocument) {
+	size := int64(len(h.html))
+
+	// Documents that could never fit aren't worth evicting everything else for
+	if c.maxBytes > 0 && size > c.maxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Replacing an entry doesn't evict it, so its size wouldn't be taken off
+	c.entries.Remove(key)
+	c.entries.Add(key, h)
+	c.bytes += size
+
+	for c.maxBytes > 0 && c.bytes > c.maxBytes {
+		if _, _, ok := c.entries.RemoveOldest(); !ok {
+			break
+		}
+	}
+}
+
+// highlightedFile is one of the files of a document, ready to be shown.
+type highlightedFile struct {
+	database.File
+
+	Anchor      string // ID of the file's section, which its lines' IDs start with
+	Highlighted template.HTML
+	Raw         template.URL // The file's raw route
+	Source      template.URL // Where to fetch the file from, if it isn't text
+	Image       bool         // Whether the file can be previewed as an image
+}
+
+// highlightDocument highlights a document's content, marking the given line
+// ranges. The result is cached, and reused for as long as the document's
+// content stays the same.
+func (s *Server) highlightDocument(document database.Document, language, theme string, lines [][2]int) (string, error) {
+	hash := document.ContentHash
+
+	if hash == "" {
+		hash = database.HashContent(document.Content)
+	}
+
+	html, err := s.highlight(highlightKey{document.ID, "", language, theme}, document.Content, hash,
+		!document.BurnAfterRead, util.HighlightOptions{})
+
+	if err != nil {
+		return "", err
+	}
+
+	return util.MarkLines(html, lines), nil
+}
+
+// highlightFiles highlights each of a document's files in its own language.
+// Their lines are linked to as #f1-L1, #f2-L1 and so on, so that they're told
+// apart.
+func (s *Server) highlightFiles(document database.Document, theme string) ([]highlightedFile, error) {
+	files := make([]highlightedFile, len(document.Files))
+
+	for i, file := range document.Files {
+		anchor := fmt.Sprintf("f%d", i+1)
+		raw := template.URL("/" + document.ID + "/raw/" + url.PathEscape(file.Name))
+
+		// Files that aren't text can't be highlighted, so images are previewed and
+		// anything else is linked to. Burned documents have no raw route left to link
+		// to, so their files are embedded instead.
+		if !util.IsText(file.ContentType) {
+			source := raw
+
+			if document.BurnAfterRead {
+				source = template.URL("data:" + file.ContentType + ";base64," +
+					base64.StdEncoding.EncodeToString([]byte(file.Content)))
+			}
+
+			files[i] = highlightedFile{File: file, Anchor: anchor, Raw: raw, Source: source, Image: util.IsImage(file.ContentType)}
+			continue
+		}
+
+		html, err := s.highlight(highlightKey{document.ID, file.Name, file.Language, theme}, file.Content,
+			file.ContentHash, !document.BurnAfterRead, util.HighlightOptions{LinePrefix: anchor + "-L"})
+
+		if err != nil {
+			return nil, err
+		}
+
+		files[i] = highlightedFile{File: file, Anchor: anchor, Raw: raw, Highlighted: template.HTML(html)}
+	}
+
+	return files, nil
+}
+
+// highlight highlights content, keeping the result if cache is set and the
+// server caches highlighted documents.
+func (s *Server) highlight(key highlightKey, content, hash string, cache bool,
+	options util.HighlightOptions) (string, error) {
+	// Content that's burned after reading shouldn't be kept around any longer than it has to be
+	if s.highlights == nil || !cache {
+		return util.Highlight(content, key.language, key.theme, options)
+	}
+
+	if cached, ok := s.highlights.Get(key); ok && cached.hash == hash {
+		return cached.html, nil
+	}
+
+	html, err := util.Highlight(content, key.language, key.theme, options)
+
+	if err != nil {
+		return "", err
+	}
+
+	s.highlights.Add(key, highlightedDocument{hash, html})
+	return html, nil
+}
+
+// theme returns the theme a page should be highlighted with: the one picked
+// with ?theme=, or the instance's default.
+func (s *Server) theme(r *http.Request) (string, error) {
+	theme := r.URL.Query().Get("theme")
+
+	if theme == "" {
+		return s.Config.Theme, nil
+	}
+
+	if !util.IsTheme(theme) {
+		return "", fmt.Errorf("unknown theme %q", theme)
+	}
+
+	return theme, nil
+}
+
+// FetchStylesheet serves the stylesheet for a highlighting theme. Stylesheets
+// never change while the server is running, so browsers may cache them.
+func (s *Server) FetchStylesheet(w http.ResponseWriter, r *http.Request) {
+	theme := chi.URLParam(r, "theme")
+
+	if !util.IsTheme(theme) {
+		util.WriteError(w, http.StatusNotFound, fmt.Errorf("unknown theme %q", theme))
+		return
+	}
+
+	css, err := util.HighlightCSS(theme)
+
+	if err != nil {
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	hash := sha256.Sum256([]byte(css))
+
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Header().Set("ETag", `"`+hex.EncodeToString(hash[:8])+`"`)
+
+	// Handles conditional requests against the ETag
+	http.ServeContent(w, r, "", time.Time{}, strings.NewReader(css))
+}
@@ -0,0 +1,302 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server_test
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFetchStylesheet(t *testing.T) {
+	s := server.NewServer(&mockConfig, &databasefakes.FakeDatabase{})
+	s.MountStatic()
+
+	req, _ := http.NewRequest(http.MethodGet, "/static/highlight/monokai.css", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Equal(t, "text/css; charset=utf-8", res.Result().Header.Get("Content-Type"))
+	require.Equal(t, "public, max-age=86400", res.Result().Header.Get("Cache-Control"))
+	require.Contains(t, res.Body.String(), ".chroma")
+
+	// Browsers that already have it don't need it sent again
+	req, _ = http.NewRequest(http.MethodGet, "/static/highlight/monokai.css", nil)
+	req.Header.Set("If-None-Match", res.Result().Header.Get("ETag"))
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusNotModified, res.Result().StatusCode)
+
+	req, _ = http.NewRequest(http.MethodGet, "/static/highlight/not-a-style.css", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusNotFound, res.Result().StatusCode)
+}
+
+func TestStaticDocumentHighlightCache(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{
+		ID:          "12345678",
+		Content:     "first",
+		ContentHash: database.HashContent("first"),
+	}, nil)
+
+	config := mockConfig
+	config.HighlightCache = 10
+
+	s := server.NewServer(&config, mockDB)
+	s.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/12345678", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), "first")
+	require.Contains(t, res.Body.String(), `href="/static/highlight/auto.css"`)
+
+	// Once the document is edited, the cached copy is no longer used
+	mockDB.GetDocumentReturns(database.Document{
+		ID:          "12345678",
+		Content:     "second",
+		ContentHash: database.HashContent("second"),
+	}, nil)
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), "second")
+	require.NotContains(t, res.Body.String(), "first")
+
+	// Marked lines are applied to the cached copy, rather than cached separately.
+	// The content is changed without its hash, so the cached copy is shown if it's used
+	mockDB.GetDocumentReturns(database.Document{
+		ID:          "12345678",
+		Content:     "third",
+		ContentHash: database.HashContent("second"),
+	}, nil)
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678?hl=1", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), "second")
+	require.Contains(t, res.Body.String(), `class="line hl"`)
+}
+
+func TestStaticDocumentHighlightCacheSize(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{
+		ID:          "12345678",
+		Content:     "first",
+		ContentHash: database.HashContent("first"),
+	}, nil)
+
+	// Highlighted documents larger than the whole cache aren't kept
+	config := mockConfig
+	config.HighlightCache = 10
+	config.HighlightCacheSize = 16
+
+	s := server.NewServer(&config, mockDB)
+	s.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/12345678", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), "first")
+
+	mockDB.GetDocumentReturns(database.Document{
+		ID:          "12345678",
+		Content:     "second",
+		ContentHash: database.HashContent("first"),
+	}, nil)
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), "second")
+}
+
+func TestStaticDocumentTheme(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "test", BurnAfterRead: true}, nil)
+
+	s := server.NewServer(&mockConfig, mockDB)
+	s.MountHandlers()
+
+	// Unknown themes are refused before the document is read, and burned
+	req, _ := http.NewRequest(http.MethodGet, "/12345678?theme=not-a-style", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusBadRequest, res.Result().StatusCode)
+	require.Equal(t, 0, mockDB.GetAndDeleteDocumentCallCount())
+
+	mockDB.GetAndDeleteDocumentReturns(database.Document{ID: "12345678", Content: "test"}, nil)
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678?theme=github", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), `data-theme="light"`)
+	require.Contains(t, res.Body.String(), `href="/static/highlight/github.css"`)
+	require.Contains(t, res.Body.String(), `<option value="github" selected>`)
+}
+
+func BenchmarkStaticDocument(b *testing.B) {
+	content := strings.Repeat("2024-01-01T00:00:00Z INFO server: handled request method=GET status=200\n", 400_000/73)
+
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{
+		ID:          "12345678",
+		Content:     content,
+		ContentHash: database.HashContent(content),
+	}, nil)
+
+	for _, size := range []int{0, 128} {
+		config := mockConfig
+		config.HighlightCache = size
+
+		s := server.NewServer(&config, mockDB)
+		s.MountHandlers()
+
+		name := "cached"
+
+		if size == 0 {
+			name = "uncached"
+		}
+
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(content)))
+
+			for i := 0; i < b.N; i++ {
+				req, _ := http.NewRequest(http.MethodGet, "/12345678", nil)
+
+				if res := executeRequest(req, s); res.Code != http.StatusOK {
+					b.Fatalf("unexpected status %d", res.Code)
+				}
+			}
+		})
+	}
+}
+
+func TestStaticDocumentLanguage(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "package main", Language: "Go"}, nil)
+
+	s := server.NewServer(&mockConfig, mockDB)
+	s.MountHandlers()
+
+	// The document is highlighted as the language it was saved with
+	req, _ := http.NewRequest(http.MethodGet, "/12345678", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), `<span class="kn">package</span>`)
+
+	// Unless an extension picks another
+	req, _ = http.NewRequest(http.MethodGet, "/12345678.txt", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.NotContains(t, res.Body.String(), `<span class="kn">package</span>`)
+}
+
+func TestStaticDocumentLines(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "one\ntwo\nthree\nfour\n", BurnAfterRead: true}, nil)
+	mockDB.GetAndDeleteDocumentReturns(database.Document{ID: "12345678", Content: "one\ntwo\nthree\nfour\n"}, nil)
+
+	s := server.NewServer(&mockConfig, mockDB)
+	s.MountHandlers()
+
+	// Invalid ranges are refused before the document is read, and burned
+	req, _ := http.NewRequest(http.MethodGet, "/12345678?hl=0", nil)
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusBadRequest, res.Result().StatusCode)
+	require.Equal(t, 0, mockDB.GetAndDeleteDocumentCallCount())
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678?hl=2-3,4", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Equal(t, 3, strings.Count(res.Body.String(), `class="line hl"`))
+}
+
+func TestStaticDocumentFiles(t *testing.T) {
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: "package main", Files: []database.File{
+		{Name: "main.go", Language: "Go", Content: "package main"},
+		{Name: "app.log", Language: "plaintext", Content: "started"},
+		{Name: "notes #1.txt", Language: "plaintext", Content: "todo"},
+	}}, nil)
+
+	s := server.NewServer(&mockConfig, mockDB)
+	s.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/12345678", nil)
+	res := executeRequest(req, s)
+
+	// Each file has its own section, highlighted as its own language, with its own line IDs
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), `<section class="file" id="f1">`)
+	require.Contains(t, res.Body.String(), `<section class="file" id="f2">`)
+	require.Contains(t, res.Body.String(), `href="/12345678/raw/app.log"`)
+	require.Contains(t, res.Body.String(), `href="/12345678/raw/notes%20%231.txt"`)
+	require.Contains(t, res.Body.String(), `<span class="kn">package</span>`)
+	require.Contains(t, res.Body.String(), `id="f2-L1"`)
+}
+
+func TestStaticDocumentBinaryFiles(t *testing.T) {
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+	mockDB := &databasefakes.FakeDatabase{}
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", Content: image, ContentType: "image/png",
+		Files: []database.File{
+			{Name: "logo.png", ContentType: "image/png", Content: image},
+			{Name: "report.pdf", ContentType: "application/pdf", Content: "%PDF-1.7\n"},
+		}}, nil)
+
+	s := server.NewServer(&mockConfig, mockDB)
+	s.MountHandlers()
+
+	req, _ := http.NewRequest(http.MethodGet, "/12345678", nil)
+	res := executeRequest(req, s)
+
+	// Images are previewed from the raw route, and anything else is linked to
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), `<img class="preview" src="/12345678/raw/logo.png" alt="logo.png" />`)
+	require.Contains(t, res.Body.String(), `href="/12345678/raw/report.pdf" download="report.pdf"`)
+	require.NotContains(t, res.Body.String(), "PNG")
+
+	// Burned documents can't be fetched again, so their images are embedded
+	mockDB.GetDocumentReturns(database.Document{ID: "12345678", BurnAfterRead: true}, nil)
+	mockDB.GetAndDeleteDocumentReturns(database.Document{ID: "12345678", BurnAfterRead: true, Content: image,
+		ContentType: "image/png", Files: []database.File{{Name: "logo.png", ContentType: "image/png", Content: image}}}, nil)
+
+	req, _ = http.NewRequest(http.MethodGet, "/12345678", nil)
+	res = executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+	require.Contains(t, res.Body.String(), `src="data:image/png;base64,iVBORw0KGgoAAAANSUhEUg=="`)
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lukewhrit/spacebin/internal/util"
+)
+
+func (s *Server) FetchRevisions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "document")
+
+	// Validate document ID
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
+		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if _, err := peekDocument(s, r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			util.WriteError(w, http.StatusNotFound, err)
+		case errors.Is(err, errBurnAfterRead):
+			util.WriteError(w, http.StatusForbidden, err)
+		default:
+			util.WriteError(w, http.StatusInternalServerError, err)
+		}
+
+		return
+	}
+
+	revisions, err := s.Database.GetRevisions(r.Context(), id)
+
+	if err != nil {
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := util.WriteJSON(w, http.StatusOK, revisions); err != nil {
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
+
+func (s *Server) FetchRawRevision(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "document")
+
+	// Validate document ID
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
+		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	n, err := strconv.Atoi(chi.URLParam(r, "revision"))
+
+	if err != nil || n < 1 {
+		util.WriteError(w, http.StatusBadRequest, fmt.Errorf("invalid revision %q", chi.URLParam(r, "revision")))
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/plain")
+
+	if _, err := peekDocument(s, r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(fmt.Sprintf("Document with ID %s not found: %s", id, err.Error())))
+		case errors.Is(err, errBurnAfterRead):
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(err.Error()))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(fmt.Sprintf("Error fetching document with ID %s: %s", id, err.Error())))
+		}
+
+		return
+	}
+
+	revision, err := s.Database.GetRevision(r.Context(), id, n)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(fmt.Sprintf("Revision %d of document with ID %s not found: %s", n, id, err.Error())))
+			return
+		}
+
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(fmt.Sprintf("Error fetching revision %d of document with ID %s: %s", n, id, err.Error())))
+		return
+	}
+
+	// Respond with only the revision's content
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(revision.Content))
+}
@@ -0,0 +1,132 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server_test
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type RevisionsSuite struct {
+	suite.Suite
+
+	srv *server.Server
+	db  *databasefakes.FakeDatabase
+}
+
+func (s *RevisionsSuite) SetupTest() {
+	s.db = &databasefakes.FakeDatabase{}
+
+	s.db.GetDocumentReturns(database.Document{ID: "12345678", Content: "edited"}, nil)
+	s.db.GetRevisionsReturns([]database.Revision{
+		{DocumentID: "12345678", Revision: 1, Content: "test"},
+		{DocumentID: "12345678", Revision: 2, Content: "edited"},
+	}, nil)
+	s.db.GetRevisionReturns(database.Revision{DocumentID: "12345678", Revision: 1, Content: "test"}, nil)
+
+	s.srv = server.NewServer(&mockConfig, s.db)
+	s.srv.MountHandlers()
+}
+
+func (s *RevisionsSuite) TestFetchRevisions() {
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678/revisions", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+
+	x, _ := io.ReadAll(res.Result().Body)
+	var body struct {
+		Payload []database.Revision
+	}
+	json.Unmarshal(x, &body)
+
+	require.Len(s.T(), body.Payload, 2)
+	require.Equal(s.T(), "test", body.Payload[0].Content)
+	require.Equal(s.T(), 2, body.Payload[1].Revision)
+}
+
+func (s *RevisionsSuite) TestFetchRawRevision() {
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678/revisions/1/raw", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), "text/plain", res.Result().Header.Get("Content-Type"))
+
+	x, _ := io.ReadAll(res.Result().Body)
+	require.Equal(s.T(), "test", string(x))
+
+	_, id, n := s.db.GetRevisionArgsForCall(0)
+	require.Equal(s.T(), "12345678", id)
+	require.Equal(s.T(), 1, n)
+}
+
+func (s *RevisionsSuite) TestFetchMissingRevision() {
+	s.db.GetRevisionReturns(database.Revision{}, sql.ErrNoRows)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678/revisions/3/raw", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+}
+
+func (s *RevisionsSuite) TestFetchBadRevision() {
+	for _, n := range []string{"0", "-1", "first"} {
+		req, _ := http.NewRequest(http.MethodGet, "/api/12345678/revisions/"+n+"/raw", nil)
+		res := executeRequest(req, s.srv)
+
+		require.Equal(s.T(), http.StatusBadRequest, res.Result().StatusCode)
+	}
+}
+
+func (s *RevisionsSuite) TestFetchRevisionsMissingDocument() {
+	s.db.GetDocumentReturns(database.Document{}, sql.ErrNoRows)
+
+	req, _ := http.NewRequest(http.MethodGet, "/api/12345678/revisions", nil)
+	res := executeRequest(req, s.srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.GetRevisionsCallCount())
+}
+
+// TestFetchBurnAfterReadRevisions tests that revisions can't be used to read a
+// burn after reading document without burning it
+func (s *RevisionsSuite) TestFetchBurnAfterReadRevisions() {
+	s.db.GetDocumentReturns(database.Document{ID: "12345678", Content: "secret", BurnAfterRead: true}, nil)
+
+	for _, path := range []string{"/api/12345678/revisions", "/api/12345678/revisions/1/raw"} {
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		res := executeRequest(req, s.srv)
+
+		require.Equal(s.T(), http.StatusForbidden, res.Result().StatusCode)
+	}
+
+	require.Equal(s.T(), 0, s.db.GetRevisionsCallCount())
+	require.Equal(s.T(), 0, s.db.GetRevisionCallCount())
+}
+
+func TestRevisionsSuite(t *testing.T) {
+	suite.Run(t, new(RevisionsSuite))
+}
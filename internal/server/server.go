@@ -30,16 +30,34 @@ import (
 	"github.com/lukewhrit/spacebin/internal/config"
 	"github.com/lukewhrit/spacebin/internal/database"
 	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 	"github.com/rs/zerolog/log"
 )
 
 //go:embed web/*
 var resources embed.FS
 
+// compressibleTypes are the content types that are compressed when a client
+// supports it. Images other than SVGs are already compressed, so they're left out.
+var compressibleTypes = []string{
+	"application/json",
+	"text/plain",
+	"text/html",
+	"text/css",
+	"text/javascript",
+	"image/svg+xml",
+}
+
 type Server struct {
 	Router   *chi.Mux
 	Config   *config.Cfg
 	Database database.Database
+
+	// Users let in with Basic Auth, along with the one in Config.Username and
+	// Config.Password. If there are none, anyone can use the server.
+	Users *util.Users
+
+	highlights *highlightCache // nil if highlighted documents aren't cached
 }
 
 func NewServer(config *config.Cfg, db database.Database) *Server {
@@ -47,6 +65,11 @@ func NewServer(config *config.Cfg, db database.Database) *Server {
 	s.Router = chi.NewRouter()
 	s.Config = config
 	s.Database = db
+
+	if config.HighlightCache > 0 {
+		s.highlights = newHighlightCache(config.HighlightCache, config.HighlightCacheSize)
+	}
+
 	return s
 }
 
@@ -78,12 +101,35 @@ func serveFiles(r chi.Router, path string, root http.FileSystem) {
 //  3. Load static content, if enabled - MountStatic()
 //  4. Mount API routes - MountHandlers()
 
+// contentTypes are the types of request body the server takes.
+var contentTypes = []string{"application/json", "multipart/form-data"}
+
+// rawContentTypes are the types of request body that are also taken when
+// creating documents with POST / or PUT /, so that they can be uploaded with
+// curl --data-binary and the like.
+var rawContentTypes = []string{"text/plain", "application/octet-stream", "application/x-www-form-urlencoded"}
+
+// allowContentType refuses request bodies the server doesn't take.
+func allowContentType(next http.Handler) http.Handler {
+	allowed := middleware.AllowContentType(contentTypes...)(next)
+	raw := middleware.AllowContentType(append(contentTypes, rawContentTypes...)...)(next)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" && (r.Method == http.MethodPost || r.Method == http.MethodPut) {
+			raw.ServeHTTP(w, r)
+			return
+		}
+
+		allowed.ServeHTTP(w, r)
+	})
+}
+
 func (s *Server) MountMiddleware() {
 	// Register middleware
 	s.Router.Use(util.Logger)
 	s.Router.Use(middleware.RequestID)
 	s.Router.Use(middleware.RealIP)
-	s.Router.Use(middleware.AllowContentType("application/json", "multipart/form-data"))
+	s.Router.Use(allowContentType)
 
 	// Ratelimiter
 	reqs, per, err := util.ParseRatelimiterString(s.Config.Ratelimiter)
@@ -96,23 +142,45 @@ func (s *Server) MountMiddleware() {
 
 	s.Router.Use(httprate.LimitAll(reqs, per))
 	s.Router.Use(middleware.Heartbeat("/ping"))
+
+	// Compression, around Recoverer so errors from panics are compressed too
+	// rather than written after the response has been finished
+	if s.Config.CompressionLevel > 0 {
+		s.Router.Use(util.Compress(s.Config.CompressionLevel, compressibleTypes...))
+	}
+
 	s.Router.Use(middleware.Recoverer)
 
 	// CORS
 	s.Router.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"https://*"},
 		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
-		ExposedHeaders:   []string{"Link"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", api.DeleteTokenHeader, api.EditTokenHeader},
+		ExposedHeaders:   []string{"Link", api.DeleteTokenHeader, api.EditTokenHeader},
 		AllowCredentials: false,
 		MaxAge:           300, // Maximum value not ignored by any of major browsers
 	}))
 
 	// Basic Auth
-	if s.Config.Username != "" && s.Config.Password != "" {
-		s.Router.Use(middleware.BasicAuth("spacebin", map[string]string{
-			s.Config.Username: s.Config.Password,
-		}))
+	users := s.Users
+
+	if users == nil {
+		users = util.NewUsers()
+	}
+
+	legacy := s.Config.Username != "" && s.Config.Password != ""
+
+	if legacy {
+		if err := s.addConfigUser(users); err != nil {
+			log.Error().
+				Err(err).
+				Msg("Basic Auth Error")
+		}
+	}
+
+	// Nobody is let in if the configured user couldn't be added, rather than everybody
+	if users.Len() > 0 || legacy {
+		s.Router.Use(basicAuth("spacebin", users))
 	}
 }
 
@@ -139,6 +207,15 @@ func (s *Server) MountStatic() {
 
 	serveFiles(s.Router, "/static/", http.FS(filesDir))
 
+	// Generate the default highlighting stylesheet now, rather than on the first request for it
+	if _, err := util.HighlightCSS(s.Config.Theme); err != nil {
+		log.Error().
+			Err(err).
+			Msg("Error generating highlighting stylesheet")
+	}
+
+	s.Router.Get("/static/highlight/{theme}.css", s.FetchStylesheet)
+
 	s.Router.Get("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
 		file, err := resources.ReadFile("web/static/robots.txt")
 
@@ -175,11 +252,20 @@ func (s *Server) MountHandlers() {
 
 	s.Router.Post("/api/", s.CreateDocument)
 	s.Router.Get("/api/{document}", s.FetchDocument)
+	s.Router.Put("/api/{document}", s.UpdateDocument)
+	s.Router.Delete("/api/{document}", s.DeleteDocument)
 	s.Router.Get("/api/{document}/raw", s.FetchRawDocument)
+	s.Router.Get("/api/{document}/raw/{filename}", s.FetchRawDocument)
+	s.Router.Get("/api/{document}/revisions", s.FetchRevisions)
+	s.Router.Get("/api/{document}/revisions/{revision}/raw", s.FetchRawRevision)
+	s.Router.Get("/api/{document}/diff/{other}", s.FetchDiff)
 
 	s.Router.Post("/", s.StaticCreateDocument)
+	s.Router.Put("/", s.StaticCreateDocument)
 	s.Router.Get("/{document}", s.StaticDocument)
 	s.Router.Get("/{document}/raw", s.FetchRawDocument)
+	s.Router.Get("/{document}/raw/{filename}", s.FetchRawDocument)
+	s.Router.Get("/{document}/diff/{other}", s.StaticDiff)
 
 	// Legacy routes
 	s.Router.Post("/v1/documents/", s.CreateDocument)
@@ -103,3 +103,83 @@ func TestRegisterHeaders(t *testing.T) {
 	require.Equal(t, "max-age=31536000; includeSubDomains; preload", res.Result().Header.Get("Strict-Transport-Security"))
 	require.Equal(t, mockConfig.ContentSecurityPolicy, res.Result().Header.Get("Content-Security-Policy"))
 }
+
+func TestCompression(t *testing.T) {
+	s := server.NewServer(&mockConfig, &databasefakes.FakeDatabase{})
+
+	s.MountMiddleware()
+	s.MountStatic()
+
+	tests := []struct {
+		path     string
+		encoding string
+	}{
+		{"/static/global.css", "gzip"},
+		{"/", "gzip"},
+		{"/static/favicon.ico", ""}, // Already compressed
+		{"/robots.txt", ""},         // Too small to be worth it
+	}
+
+	for _, tt := range tests {
+		req, _ := http.NewRequest(http.MethodGet, tt.path, nil)
+		req.Header.Set("Accept-Encoding", "gzip")
+		res := executeRequest(req, s)
+
+		checkResponseCode(t, http.StatusOK, res.Result().StatusCode)
+		require.Equal(t, tt.encoding, res.Result().Header.Get("Content-Encoding"), tt.path)
+	}
+}
+
+// TestCompressionPanic tests that a handler's panic is answered with an error,
+// rather than the compressor finishing the response first
+func TestCompressionPanic(t *testing.T) {
+	s := server.NewServer(&mockConfig, &databasefakes.FakeDatabase{})
+	s.MountMiddleware()
+	s.Router.Get("/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("oops")
+	})
+
+	req, _ := http.NewRequest(http.MethodGet, "/panic", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	res := executeRequest(req, s)
+
+	checkResponseCode(t, http.StatusInternalServerError, res.Result().StatusCode)
+}
+
+func TestAllowContentType(t *testing.T) {
+	s := server.NewServer(&mockConfig, &databasefakes.FakeDatabase{})
+	s.MountMiddleware()
+	s.MountHandlers()
+
+	tests := []struct {
+		method, path, contentType string
+		want                      int
+	}{
+		{http.MethodPost, "/api/", "application/json", http.StatusOK},
+		{http.MethodPost, "/api/", "text/plain", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/api/", "application/octet-stream", http.StatusUnsupportedMediaType},
+		{http.MethodPost, "/api/", "application/x-www-form-urlencoded", http.StatusUnsupportedMediaType},
+		{http.MethodPut, "/api/12345678", "text/plain", http.StatusUnsupportedMediaType},
+
+		// Raw bodies can only be uploaded to the website
+		{http.MethodPost, "/", "text/plain", http.StatusOK},
+		{http.MethodPut, "/", "application/octet-stream", http.StatusOK},
+		{http.MethodPost, "/", "application/x-www-form-urlencoded", http.StatusOK},
+		{http.MethodPost, "/", "application/xml", http.StatusUnsupportedMediaType},
+	}
+
+	for _, tt := range tests {
+		body := `{"content": "Hello, world!"}`
+
+		if tt.contentType != "application/json" {
+			body = "Hello, world!"
+		}
+
+		req, _ := http.NewRequest(tt.method, tt.path, strings.NewReader(body))
+		req.Header.Set("Content-Type", tt.contentType)
+		req.Header.Set("Accept", "text/plain")
+		res := executeRequest(req, s)
+
+		require.Equal(t, tt.want, res.Result().StatusCode, "%s %s %s", tt.method, tt.path, tt.contentType)
+	}
+}
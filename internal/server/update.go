@@ -0,0 +1,120 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
+)
+
+func (s *Server) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "document")
+
+	// Validate document ID
+	if len(id) != s.Config.IDLength && !isCustomDocument(s.Config.Documents, id) {
+		err := fmt.Errorf("id is of length %d, should be %d", len(id), s.Config.IDLength)
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	document, err := lookupDocument(s, r.Context(), id)
+
+	if err != nil {
+		// If the document is not found (ErrNoRows), return the error with a 404
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		// Otherwise, return the error with a 500
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	// Only whoever created the document has its edit token. Documents without
+	// one, like custom documents, can't be edited at all.
+	if !util.CheckToken(r.Header.Get(api.EditTokenHeader), document.EditTokenHash) {
+		util.WriteError(w, http.StatusForbidden, errors.New("invalid edit token"))
+		return
+	}
+
+	// Revisions only keep a single file's content
+	if len(document.Files) > 0 {
+		util.WriteError(w, http.StatusConflict, errors.New("documents uploaded as files can't be edited"))
+		return
+	}
+
+	// Edits use the same body as new documents, but only the content is changed
+	body, err := util.HandleBody(s.Config.MaxSize, w, r)
+
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+
+		if errors.As(err, &tooLarge) {
+			util.WriteError(w, http.StatusRequestEntityTooLarge, err)
+			return
+		}
+
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if err := util.ValidateBody(s.Config.MaxSize, body); err != nil {
+		util.WriteError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	if len(body.Files) > 0 {
+		util.WriteError(w, http.StatusBadRequest, errors.New("files can't be added to a document once it's created"))
+		return
+	}
+
+	revision, err := s.Database.UpdateDocument(r.Context(), database.Document{ID: id, Content: body.Content})
+
+	if err != nil {
+		// The document may have been deleted by another request in the meantime
+		if errors.Is(err, sql.ErrNoRows) {
+			util.WriteError(w, http.StatusNotFound, err)
+			return
+		}
+
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	document, err = s.Database.GetDocument(r.Context(), id)
+
+	if err != nil {
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	if err := util.WriteJSON(w, http.StatusOK, api.UpdateResponse{
+		Document: apiDocument(document),
+		Revision: revision.Revision,
+	}); err != nil {
+		util.WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+}
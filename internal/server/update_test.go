@@ -0,0 +1,138 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package server_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
+	"github.com/stretchr/testify/require"
+	"github.com/stretchr/testify/suite"
+)
+
+type UpdateDocumentSuite struct {
+	suite.Suite
+
+	srv *server.Server
+	db  *databasefakes.FakeDatabase
+}
+
+func (s *UpdateDocumentSuite) SetupTest() {
+	s.db = &databasefakes.FakeDatabase{}
+
+	s.db.GetDocumentReturns(database.Document{
+		ID:            "12345678",
+		Content:       "test",
+		EditTokenHash: util.HashToken("token"),
+	}, nil)
+	s.db.UpdateDocumentReturns(database.Revision{DocumentID: "12345678", Revision: 2, Content: "edited"}, nil)
+
+	s.srv = server.NewServer(&mockConfig, s.db)
+	s.srv.MountHandlers()
+}
+
+func newUpdateRequest(id, token, content string) *http.Request {
+	req, _ := http.NewRequest(http.MethodPut, "/api/"+id,
+		bytes.NewReader([]byte(`{"content": "`+content+`"}`)),
+	)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(api.EditTokenHeader, token)
+
+	return req
+}
+
+func (s *UpdateDocumentSuite) TestUpdateDocument() {
+	res := executeRequest(newUpdateRequest("12345678", "token", "edited"), s.srv)
+
+	require.Equal(s.T(), http.StatusOK, res.Result().StatusCode)
+	require.Equal(s.T(), 1, s.db.UpdateDocumentCallCount())
+
+	_, document := s.db.UpdateDocumentArgsForCall(0)
+	require.Equal(s.T(), "12345678", document.ID)
+	require.Equal(s.T(), "edited", document.Content)
+
+	x, _ := io.ReadAll(res.Result().Body)
+	var body struct {
+		Payload api.UpdateResponse
+	}
+	json.Unmarshal(x, &body)
+
+	require.Equal(s.T(), 2, body.Payload.Revision)
+}
+
+func (s *UpdateDocumentSuite) TestUpdateDocumentWrongToken() {
+	for _, token := range []string{"", "wrong"} {
+		res := executeRequest(newUpdateRequest("12345678", token, "edited"), s.srv)
+
+		require.Equal(s.T(), http.StatusForbidden, res.Result().StatusCode)
+	}
+
+	require.Equal(s.T(), 0, s.db.UpdateDocumentCallCount())
+}
+
+// TestUpdateDocumentWithoutToken tests that documents without an edit token,
+// like custom documents, can't be edited
+func (s *UpdateDocumentSuite) TestUpdateDocumentWithoutToken() {
+	s.db.GetDocumentReturns(database.Document{ID: "12345678", Content: "test"}, nil)
+
+	res := executeRequest(newUpdateRequest("12345678", "", "edited"), s.srv)
+
+	require.Equal(s.T(), http.StatusForbidden, res.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.UpdateDocumentCallCount())
+}
+
+func (s *UpdateDocumentSuite) TestUpdateDocumentFiles() {
+	s.db.GetDocumentReturns(database.Document{
+		ID:            "12345678",
+		Content:       "test",
+		EditTokenHash: util.HashToken("token"),
+		Files:         []database.File{{Name: "a.txt", Content: "test"}, {Name: "b.txt", Content: "test"}},
+	}, nil)
+
+	res := executeRequest(newUpdateRequest("12345678", "token", "edited"), s.srv)
+
+	require.Equal(s.T(), http.StatusConflict, res.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.UpdateDocumentCallCount())
+}
+
+func (s *UpdateDocumentSuite) TestUpdateMissingDocument() {
+	s.db.GetDocumentReturns(database.Document{}, sql.ErrNoRows)
+
+	res := executeRequest(newUpdateRequest("12345678", "token", "edited"), s.srv)
+
+	require.Equal(s.T(), http.StatusNotFound, res.Result().StatusCode)
+}
+
+func (s *UpdateDocumentSuite) TestUpdateDocumentEmptyContent() {
+	res := executeRequest(newUpdateRequest("12345678", "token", ""), s.srv)
+
+	require.Equal(s.T(), http.StatusBadRequest, res.Result().StatusCode)
+	require.Equal(s.T(), 0, s.db.UpdateDocumentCallCount())
+}
+
+func TestUpdateDocumentSuite(t *testing.T) {
+	suite.Run(t, new(UpdateDocumentSuite))
+}
@@ -13,3 +13,74 @@ document.querySelector('textarea')?.addEventListener('keydown', function (e) {
     this.selectionStart = this.selectionEnd = start + 1;
   }
 });
+
+// Reloads the document with the picked highlighting theme
+document.querySelector('#theme')?.addEventListener('change', function () {
+  const url = new URL(window.location.href);
+  url.searchParams.set('theme', this.value);
+
+  window.location.href = url.toString();
+});
+
+// Marks the lines linked to with #L10 or #L10-L25. Clicking a line number links
+// to it, and shift-clicking another links to every line between them. Files of
+// documents with several have their own prefix, like #f2-L10-L25.
+let marked = [];
+let anchor = null;
+
+function markLines() {
+  const match = window.location.hash.match(/^#((?:f\d+-)?L)(\d+)(?:-L(\d+))?$/);
+
+  marked.forEach((line) => line.classList.remove('hl'));
+  marked = [];
+
+  if (!match) {
+    return null;
+  }
+
+  const [, prefix, start, end = start] = match;
+
+  for (let n = Math.min(start, end); n <= Math.max(start, end); n++) {
+    const line = document.getElementById(prefix + n)?.parentElement;
+
+    if (!line) {
+      break;
+    }
+
+    // Lines marked with ?hl= stay marked
+    if (!line.classList.contains('hl')) {
+      line.classList.add('hl');
+      marked.push(line);
+    }
+  }
+
+  return { prefix, line: Number(start) };
+}
+
+document.querySelectorAll('.chroma .lnlinks').forEach((link) => {
+  link.addEventListener('click', function (e) {
+    e.preventDefault();
+
+    const [, prefix, line] = this.getAttribute('href').match(/^#(.*L)(\d+)$/);
+    const n = Number(line);
+
+    if (e.shiftKey && anchor?.prefix === prefix && anchor.line !== n) {
+      history.replaceState(null, '', `#${prefix}${Math.min(anchor.line, n)}-L${Math.max(anchor.line, n)}`);
+    } else {
+      history.replaceState(null, '', `#${prefix}${n}`);
+      anchor = { prefix, line: n };
+    }
+
+    markLines();
+  });
+});
+
+window.addEventListener('hashchange', () => {
+  anchor = markLines();
+});
+
+anchor = markLines();
+
+if (anchor !== null) {
+  document.getElementById(anchor.prefix + anchor.line)?.scrollIntoView();
+}
@@ -0,0 +1,281 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"bufio"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// compressMinSize is the smallest response body, in bytes, that will be
+// compressed. Anything smaller isn't worth the overhead.
+const compressMinSize = 1024
+
+type encoder interface {
+	io.WriteCloser
+	Reset(w io.Writer)
+}
+
+// Compress is a middleware that compresses response bodies with zstd or gzip,
+// depending on what the client accepts, at the given level. Only responses
+// whose Content-Type is in types and that are at least compressMinSize bytes
+// long are compressed. It must be mounted before any middleware that recovers
+// from panics, so the response is only finished once they've written theirs.
+func Compress(level int, types ...string) func(http.Handler) http.Handler {
+	pools := map[string]*sync.Pool{
+		"zstd": {New: func() interface{} {
+			enc, _ := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.EncoderLevelFromZstd(level)),
+				zstd.WithEncoderConcurrency(1))
+			return enc
+		}},
+		"gzip": {New: func() interface{} {
+			enc, _ := gzip.NewWriterLevel(nil, min(max(level, gzip.BestSpeed), gzip.BestCompression))
+			return enc
+		}},
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if encoding == "" || r.Method == http.MethodHead || r.Header.Get("Range") != "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			cw := &compressWriter{
+				ResponseWriter: w,
+				encoding:       encoding,
+				pool:           pools[encoding],
+				types:          types,
+				status:         http.StatusOK,
+			}
+
+			// If the handler panics, the response is left for whatever recovers from
+			// it rather than being finished as if nothing went wrong
+			next.ServeHTTP(cw, r)
+			cw.Close()
+		})
+	}
+}
+
+// negotiateEncoding picks the preferred encoding out of an Accept-Encoding
+// header, or returns an empty string if the client doesn't accept one we support.
+func negotiateEncoding(header string) string {
+	accepted := map[string]bool{}
+
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				q = parsed
+			}
+		}
+
+		accepted[strings.ToLower(strings.TrimSpace(name))] = q > 0
+	}
+
+	for _, encoding := range []string{"zstd", "gzip"} {
+		if accepted[encoding] {
+			return encoding
+		}
+	}
+
+	return ""
+}
+
+// compressWriter buffers the start of a response until it knows whether the
+// response is worth compressing, then either compresses the rest or passes it through.
+type compressWriter struct {
+	http.ResponseWriter
+
+	encoding string
+	pool     *sync.Pool
+	types    []string
+
+	status      int
+	wroteHeader bool
+	decided     bool
+	buf         []byte
+	enc         encoder
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	if cw.wroteHeader {
+		return
+	}
+
+	cw.wroteHeader = true
+	cw.status = status
+
+	// Responses without a body can't be compressed, so there's nothing to wait for
+	if status < http.StatusOK || status == http.StatusNoContent || status == http.StatusNotModified {
+		cw.decide(false)
+	}
+}
+
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if !cw.wroteHeader {
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if !cw.decided {
+		cw.buf = append(cw.buf, p...)
+
+		if len(cw.buf) < compressMinSize {
+			return len(p), nil
+		}
+
+		buffered := cw.buf
+		cw.buf = nil
+
+		if _, err := cw.start(buffered); err != nil {
+			return 0, err
+		}
+
+		return len(p), nil
+	}
+
+	if cw.enc != nil {
+		return cw.enc.Write(p)
+	}
+
+	return cw.ResponseWriter.Write(p)
+}
+
+// start decides whether to compress the response based on what has been
+// buffered so far, and writes it out.
+func (cw *compressWriter) start(buffered []byte) (int, error) {
+	cw.decide(len(buffered) >= compressMinSize && cw.compressible(buffered))
+
+	if cw.enc != nil {
+		return cw.enc.Write(buffered)
+	}
+
+	return cw.ResponseWriter.Write(buffered)
+}
+
+func (cw *compressWriter) compressible(buffered []byte) bool {
+	h := cw.Header()
+
+	if h.Get("Content-Encoding") != "" || cw.status == http.StatusPartialContent {
+		return false
+	}
+
+	if h.Get("Content-Type") == "" {
+		h.Set("Content-Type", http.DetectContentType(buffered))
+	}
+
+	contentType, _, _ := strings.Cut(h.Get("Content-Type"), ";")
+
+	for _, t := range cw.types {
+		if strings.EqualFold(strings.TrimSpace(contentType), t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decide sends the response headers, setting up an encoder first if compress is true.
+func (cw *compressWriter) decide(compress bool) {
+	if cw.decided {
+		return
+	}
+
+	cw.decided = true
+
+	if compress {
+		h := cw.Header()
+		h.Del("Content-Length")
+		h.Set("Content-Encoding", cw.encoding)
+
+		cw.enc = cw.pool.Get().(encoder)
+		cw.enc.Reset(cw.ResponseWriter)
+	}
+
+	cw.ResponseWriter.WriteHeader(cw.status)
+}
+
+// Close writes out anything still buffered and finishes the compressed stream.
+// If nothing was written, neither is anything here.
+func (cw *compressWriter) Close() error {
+	if !cw.decided && !cw.wroteHeader {
+		return nil
+	}
+
+	if !cw.decided {
+		buffered := cw.buf
+		cw.buf = nil
+
+		if _, err := cw.start(buffered); err != nil {
+			return err
+		}
+	}
+
+	if cw.enc == nil {
+		return nil
+	}
+
+	err := cw.enc.Close()
+	cw.enc.Reset(nil)
+	cw.pool.Put(cw.enc)
+	cw.enc = nil
+
+	return err
+}
+
+func (cw *compressWriter) Flush() {
+	if !cw.decided {
+		buffered := cw.buf
+		cw.buf = nil
+		cw.start(buffered)
+	}
+
+	if f, ok := cw.enc.(interface{ Flush() error }); ok {
+		f.Flush()
+	}
+
+	if f, ok := cw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (cw *compressWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := cw.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, errors.New("http.Hijacker is not implemented by the underlying writer")
+}
+
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
@@ -0,0 +1,125 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func compressRequest(t *testing.T, acceptEncoding, contentType, body string) *httptest.ResponseRecorder {
+	handler := util.Compress(5, "text/plain")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.Write([]byte(body))
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	require.Equal(t, http.StatusOK, rr.Code)
+	require.Equal(t, "Accept-Encoding", rr.Header().Get("Vary"))
+
+	return rr
+}
+
+func TestCompressGzip(t *testing.T) {
+	body := strings.Repeat("Hello, world! ", 200)
+	rr := compressRequest(t, "gzip, deflate", "text/plain; charset=utf-8", body)
+
+	require.Equal(t, "gzip", rr.Header().Get("Content-Encoding"))
+
+	r, err := gzip.NewReader(rr.Body)
+	require.NoError(t, err)
+
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, body, string(decompressed))
+}
+
+func TestCompressZstd(t *testing.T) {
+	body := strings.Repeat("Hello, world! ", 200)
+	rr := compressRequest(t, "gzip, zstd", "text/plain", body)
+
+	require.Equal(t, "zstd", rr.Header().Get("Content-Encoding"))
+
+	r, err := zstd.NewReader(rr.Body)
+	require.NoError(t, err)
+	defer r.Close()
+
+	decompressed, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, body, string(decompressed))
+}
+
+func TestCompressSkipped(t *testing.T) {
+	body := strings.Repeat("Hello, world! ", 200)
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		contentType    string
+		body           string
+	}{
+		{"Tiny Response", "gzip", "text/plain", "Hello, world!"},
+		{"Uncompressible Type", "gzip", "image/x-icon", body},
+		{"Unsupported Encoding", "br", "text/plain", body},
+		{"Refused Encoding", "gzip;q=0, zstd;q=0", "text/plain", body},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := compressRequest(t, tt.acceptEncoding, tt.contentType, tt.body)
+
+			require.Empty(t, rr.Header().Get("Content-Encoding"))
+			require.Equal(t, tt.body, rr.Body.String())
+		})
+	}
+}
+
+// headerRecorder records whether a response's header was written.
+type headerRecorder struct {
+	*httptest.ResponseRecorder
+	wroteHeader bool
+}
+
+func (r *headerRecorder) WriteHeader(status int) {
+	r.wroteHeader = true
+	r.ResponseRecorder.WriteHeader(status)
+}
+
+func TestCompressNothingWritten(t *testing.T) {
+	handler := util.Compress(5, "text/plain")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	rr := &headerRecorder{ResponseRecorder: httptest.NewRecorder()}
+	handler.ServeHTTP(rr, req)
+
+	require.False(t, rr.wroteHeader)
+}
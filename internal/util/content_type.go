@@ -0,0 +1,52 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// TextContentType is the content type of documents written as text.
+const TextContentType = "text/plain; charset=utf-8"
+
+// DetectContentType sniffs the MIME type of uploaded content. Anything that
+// looks like text is served as plain text, so an upload can never be served as
+// a web page of its own.
+func DetectContentType(content string) string {
+	contentType := http.DetectContentType([]byte(content[:min(len(content), 512)]))
+	mediaType, _, err := mime.ParseMediaType(contentType)
+
+	if err != nil || strings.HasPrefix(mediaType, "text/") {
+		return TextContentType
+	}
+
+	return mediaType
+}
+
+// IsText reports whether content of a type can be shown, highlighted and
+// edited as text. Documents saved before content types were recorded have none,
+// and are all text.
+func IsText(contentType string) bool {
+	return contentType == "" || strings.HasPrefix(contentType, "text/")
+}
+
+// IsImage reports whether content of a type can be previewed as an image.
+func IsImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
@@ -0,0 +1,51 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{"Hello, world!", util.TextContentType},
+		{"<!DOCTYPE html><html><script>alert(1)</script></html>", util.TextContentType},
+		{"<?xml version=\"1.0\"?><svg></svg>", util.TextContentType},
+		{"\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", "image/png"},
+		{"GIF89a\x01\x00\x01\x00", "image/gif"},
+		{"%PDF-1.7\n", "application/pdf"},
+		{"\x00\x01\x02\x03", "application/octet-stream"},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, test.expected, util.DetectContentType(test.content), test.content)
+	}
+}
+
+func TestIsText(t *testing.T) {
+	require.True(t, util.IsText(""))
+	require.True(t, util.IsText(util.TextContentType))
+	require.False(t, util.IsText("image/png"))
+	require.True(t, util.IsImage("image/png"))
+	require.False(t, util.IsImage("application/pdf"))
+}
@@ -0,0 +1,150 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// diffContext is the number of unchanged lines shown around each change.
+const diffContext = 3
+
+// MaxDiffLines is the most lines two texts can have between them to be diffed.
+// Diffing takes time quadratic in the number of lines at worst, so longer texts
+// aren't compared.
+const MaxDiffLines = 10_000
+
+// ErrDiffTooLarge is returned by Diff for texts with more than MaxDiffLines
+// lines between them.
+var ErrDiffTooLarge = errors.New("documents are too long to diff")
+
+// DiffHunk is a group of nearby changes in a unified diff. Each line is
+// prefixed with " " if it's unchanged, "-" if it was removed or "+" if it was added.
+type DiffHunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// Header returns the hunk's "@@ -1,3 +1,4 @@" line.
+func (h DiffHunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(h.OldStart, h.OldLines), hunkRange(h.NewStart, h.NewLines))
+}
+
+// hunkRange formats one side of a hunk header, leaving out the length if it's 1.
+func hunkRange(start, lines int) string {
+	if lines == 1 {
+		return fmt.Sprint(start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, lines)
+}
+
+// splitLines splits text into lines, without a trailing empty line if text ends
+// with a newline.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// Diff compares two texts line by line, returning the hunks of a unified diff
+// from a to b. It returns no hunks if the texts are the same.
+func Diff(a, b string) ([]DiffHunk, error) {
+	aLines, bLines := splitLines(a), splitLines(b)
+
+	if n := len(aLines) + len(bLines); n > MaxDiffLines {
+		return nil, fmt.Errorf("%w: they have %d lines between them, at most %d can be compared",
+			ErrDiffTooLarge, n, MaxDiffLines)
+	}
+
+	matcher := difflib.NewMatcher(aLines, bLines)
+	hunks := []DiffHunk{}
+
+	for _, group := range matcher.GetGroupedOpCodes(diffContext) {
+		first, last := group[0], group[len(group)-1]
+		hunk := DiffHunk{
+			OldStart: first.I1 + 1,
+			OldLines: last.I2 - first.I1,
+			NewStart: first.J1 + 1,
+			NewLines: last.J2 - first.J1,
+		}
+
+		// Empty ranges start at the line before them, like in GNU diff
+		if hunk.OldLines == 0 {
+			hunk.OldStart--
+		}
+
+		if hunk.NewLines == 0 {
+			hunk.NewStart--
+		}
+
+		for _, op := range group {
+			if op.Tag == 'e' {
+				for _, line := range aLines[op.I1:op.I2] {
+					hunk.Lines = append(hunk.Lines, " "+line)
+				}
+
+				continue
+			}
+
+			if op.Tag == 'r' || op.Tag == 'd' {
+				for _, line := range aLines[op.I1:op.I2] {
+					hunk.Lines = append(hunk.Lines, "-"+line)
+				}
+			}
+
+			if op.Tag == 'r' || op.Tag == 'i' {
+				for _, line := range bLines[op.J1:op.J2] {
+					hunk.Lines = append(hunk.Lines, "+"+line)
+				}
+			}
+		}
+
+		hunks = append(hunks, hunk)
+	}
+
+	return hunks, nil
+}
+
+// UnifiedDiff formats hunks as a unified diff between the files named from and to.
+func UnifiedDiff(from, to string, hunks []DiffHunk) string {
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	w := new(strings.Builder)
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", from, to)
+
+	for _, hunk := range hunks {
+		fmt.Fprintln(w, hunk.Header())
+
+		for _, line := range hunk.Lines {
+			fmt.Fprintln(w, line)
+		}
+	}
+
+	return w.String()
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		unified string
+	}{
+		{
+			name: "Identical",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+		},
+		{
+			name:    "Changed Line",
+			a:       "port=80\nhost=localhost\n",
+			b:       "port=8080\nhost=localhost\n",
+			unified: "--- a\n+++ b\n@@ -1,2 +1,2 @@\n-port=80\n+port=8080\n host=localhost\n",
+		},
+		{
+			name:    "Added To Empty",
+			a:       "",
+			b:       "hello\n",
+			unified: "--- a\n+++ b\n@@ -0,0 +1 @@\n+hello\n",
+		},
+		{
+			name:    "Removed Everything",
+			a:       "one\ntwo",
+			b:       "",
+			unified: "--- a\n+++ b\n@@ -1,2 +0,0 @@\n-one\n-two\n",
+		},
+		{
+			name: "Separate Hunks",
+			a:    "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			b:    "one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			unified: "--- a\n+++ b\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n" +
+				"@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hunks, err := Diff(tt.a, tt.b)
+			require.NoError(t, err)
+			require.Equal(t, tt.unified, UnifiedDiff("a", "b", hunks))
+		})
+	}
+}
+
+func TestDiffHunks(t *testing.T) {
+	hunks, err := Diff("a\nb\nc\n", "a\nc\nd\n")
+	require.NoError(t, err)
+
+	require.Equal(t, []DiffHunk{{
+		OldStart: 1,
+		OldLines: 3,
+		NewStart: 1,
+		NewLines: 3,
+		Lines:    []string{" a", "-b", " c", "+d"},
+	}}, hunks)
+}
+
+func TestDiffTooLarge(t *testing.T) {
+	half := strings.Repeat("line\n", MaxDiffLines/2)
+
+	_, err := Diff(half, half)
+	require.NoError(t, err)
+
+	_, err = Diff(half, half+"one more\n")
+	require.ErrorIs(t, err, ErrDiffTooLarge)
+}
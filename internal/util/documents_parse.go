@@ -0,0 +1,46 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ParseDocumentsList parses the entries of SPIRIT_DOCUMENTS into a map of
+// document IDs to the files they are loaded from. Entries are either a bare ID,
+// for documents that are managed by hand, or of the form id=path.
+func ParseDocumentsList(entries []string) (map[string]string, error) {
+	documents := make(map[string]string, len(entries))
+
+	for _, entry := range entries {
+		id, path, _ := strings.Cut(entry, "=")
+		id, path = strings.TrimSpace(id), strings.TrimSpace(path)
+
+		if id == "" {
+			return nil, fmt.Errorf("documents list invalid: entry %q has no id", entry)
+		}
+
+		if _, ok := documents[id]; ok {
+			return nil, fmt.Errorf("documents list invalid: %q is listed more than once", id)
+		}
+
+		documents[id] = path
+	}
+
+	return documents, nil
+}
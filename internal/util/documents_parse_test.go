@@ -0,0 +1,45 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDocumentsList(t *testing.T) {
+	documents, err := util.ParseDocumentsList([]string{"about=./docs/about.md", "rules = rules.txt", "legacy"})
+
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{
+		"about":  "./docs/about.md",
+		"rules":  "rules.txt",
+		"legacy": "",
+	}, documents)
+}
+
+func TestParseDocumentsListNoID(t *testing.T) {
+	_, err := util.ParseDocumentsList([]string{"=./docs/about.md"})
+	require.Error(t, err)
+}
+
+func TestParseDocumentsListDuplicate(t *testing.T) {
+	_, err := util.ParseDocumentsList([]string{"about=about.md", "about=other.md"})
+	require.Error(t, err)
+}
@@ -17,56 +17,297 @@
 package util
 
 import (
+	"bytes"
 	"embed"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
-	"math"
+	"io"
+	"mime"
 	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
 	"strings"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/lukewhrit/spacebin/pkg/api"
 	"github.com/rs/zerolog/log"
 )
 
-type CreateRequest struct {
-	Content string
+// validateFile checks one of several files uploaded as a single document.
+func validateFile(value interface{}) error {
+	f, _ := value.(api.CreateFile)
+
+	return validation.ValidateStruct(&f,
+		validation.Field(&f.Name, validation.Required, validation.Length(1, 255),
+			validation.Match(regexp.MustCompile(`^[^/\\]+$`)).Error("must not contain slashes"),
+			validation.NotIn(".", "..")),
+		validation.Field(&f.Content, validation.Required),
+		validation.Field(&f.Language, validation.By(isLanguage)),
+	)
 }
 
-func ValidateBody(maxSize int, body CreateRequest) error {
+func ValidateBody(maxSize int, body api.CreateRequest) error {
 	return validation.ValidateStruct(&body,
-		validation.Field(&body.Content, validation.Required,
-			validation.Length(2, maxSize)),
+		validation.Field(&body.Content,
+			validation.When(len(body.Files) == 0, validation.Required, validation.Length(2, maxSize)).
+				Else(validation.Empty.Error("must be blank when uploading files"))),
+		validation.Field(&body.ExpiresIn, validation.Min(int64(0))),
+		validation.Field(&body.Language, validation.By(isLanguage)),
+		validation.Field(&body.Files, validation.Each(validation.By(validateFile)),
+			validation.By(func(value interface{}) error {
+				return validateFiles(maxSize, body.Files)
+			})),
 	)
 }
 
-// HandleBody figures out whether a incoming request is in JSON or multipart/form-data and decodes it appropriately
-func HandleBody(maxSize int, r *http.Request) (CreateRequest, error) {
+// validateFiles checks that files have different names, and fit within
+// maxSize together.
+func validateFiles(maxSize int, files []api.CreateFile) error {
+	names := make(map[string]bool, len(files))
+	size := 0
+
+	for _, file := range files {
+		if names[file.Name] {
+			return fmt.Errorf("more than one file is named %q", file.Name)
+		}
+
+		names[file.Name] = true
+		size += len(file.Content)
+	}
+
+	if size > maxSize {
+		return fmt.Errorf("the total length of the files must be no more than %d", maxSize)
+	}
+
+	return nil
+}
+
+// isLanguage is a validation rule for language names Chroma knows.
+func isLanguage(value interface{}) error {
+	name, _ := value.(string)
+
+	if _, ok := Language(name); name != "" && !ok {
+		return fmt.Errorf("unknown language %q", name)
+	}
+
+	return nil
+}
+
+// HandleBody figures out whether a incoming request is JSON, multipart/form-data
+// or a raw body and decodes it appropriately. The body is read as it arrives,
+// and no more than maxSize bytes of it are read at all; larger requests fail
+// with an *http.MaxBytesError.
+func HandleBody(maxSize int, w http.ResponseWriter, r *http.Request) (api.CreateRequest, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize))
+
+	body, err := decodeBody(r)
+
+	if err != nil {
+		return api.CreateRequest{}, err
+	}
+
+	// Options can also be given in the query string, for bodies that have nowhere
+	// else to put them
+	return applyQuery(body, r.URL.Query())
+}
+
+func decodeBody(r *http.Request) (api.CreateRequest, error) {
 	// Ignore charset or boundary fields, just get type of content
 	switch strings.Split(r.Header.Get("Content-Type"), ";")[0] {
 	case "application/json":
-		resp := make(map[string]string)
+		var body api.CreateRequest
 
-		if err := json.NewDecoder(r.Body).Decode(&resp); err != nil {
-			return CreateRequest{}, err
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			return api.CreateRequest{}, err
 		}
 
-		return CreateRequest{
-			Content: resp["content"],
-		}, nil
+		return body, nil
 	case "multipart/form-data":
-		err := r.ParseMultipartForm(int64(float64(maxSize) * math.Pow(1024, 2)))
+		return readMultipartBody(r)
+	case "application/x-www-form-urlencoded":
+		return readFormBody(r)
+	case "text/plain", "application/octet-stream":
+		return readRawBody(r)
+	}
+
+	return api.CreateRequest{}, nil
+}
+
+// applyQuery sets the options given in a request's query string, which take
+// precedence over those in its body.
+func applyQuery(body api.CreateRequest, query url.Values) (api.CreateRequest, error) {
+	var err error
+
+	if v := query.Get("language"); v != "" {
+		body.Language = v
+	}
+
+	if v := query.Get("expires_in"); v != "" {
+		if body.ExpiresIn, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return api.CreateRequest{}, fmt.Errorf("expires_in: %w", err)
+		}
+	}
+
+	if v := query.Get("burn_after_read"); v != "" {
+		if body.BurnAfterRead, err = strconv.ParseBool(v); err != nil {
+			return api.CreateRequest{}, fmt.Errorf("burn_after_read: %w", err)
+		}
+	}
+
+	return body, nil
+}
+
+// readMultipartBody reads a multipart/form-data body a part at a time, rather
+// than spooling it to memory or disk first.
+func readMultipartBody(r *http.Request) (api.CreateRequest, error) {
+	reader, err := r.MultipartReader()
+
+	if err != nil {
+		return api.CreateRequest{}, err
+	}
+
+	var body api.CreateRequest
+
+	for {
+		part, err := reader.NextPart()
+
+		if errors.Is(err, io.EOF) {
+			return body, nil
+		}
+
+		if err != nil {
+			return api.CreateRequest{}, err
+		}
+
+		field := part.FormName()
+		value, err := io.ReadAll(part)
 
 		if err != nil {
-			return CreateRequest{}, err
+			return api.CreateRequest{}, fmt.Errorf("%s: %w", field, err)
 		}
 
-		return CreateRequest{
-			Content: r.FormValue("content"),
-		}, nil
+		switch field {
+		case "content":
+			body.Content = string(value)
+		case "language":
+			body.Language = string(value)
+		case "expires_in":
+			if body.ExpiresIn, err = strconv.ParseInt(string(value), 10, 64); err != nil {
+				return api.CreateRequest{}, fmt.Errorf("expires_in: %w", err)
+			}
+		case "burn_after_read":
+			if body.BurnAfterRead, err = strconv.ParseBool(string(value)); err != nil {
+				return api.CreateRequest{}, fmt.Errorf("burn_after_read: %w", err)
+			}
+		// Every file part named "files" is one of the document's files, and a
+		// single file part named "file" is a document of just that file
+		case "file", "files":
+			if part.FileName() == "" {
+				return api.CreateRequest{}, fmt.Errorf("%s: must be a file", field)
+			}
+
+			body.Files = append(body.Files, api.CreateFile{
+				Name:        part.FileName(),
+				Content:     string(value),
+				ContentType: DetectContentType(string(value)),
+			})
+		}
+	}
+}
+
+// readRawBody reads a body that is the document's content itself. Text is
+// saved as the document's content, and anything else as a file, named by the
+// request's Content-Disposition header if it has one.
+func readRawBody(r *http.Request) (api.CreateRequest, error) {
+	value, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		return api.CreateRequest{}, err
+	}
+
+	return rawBody(r, string(value)), nil
+}
+
+// readFormBody reads an application/x-www-form-urlencoded body. Forms with a
+// content field are read like multipart ones, but curl sends --data-binary
+// bodies as forms too, and those are really just the document's content.
+func readFormBody(r *http.Request) (api.CreateRequest, error) {
+	value, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		return api.CreateRequest{}, err
+	}
+
+	form, err := url.ParseQuery(string(value))
+
+	if err != nil || !form.Has("content") {
+		return rawBody(r, string(value)), nil
+	}
+
+	return applyQuery(api.CreateRequest{Content: form.Get("content")}, form)
+}
+
+// rawBody describes the content of a raw body as a document. Text is its
+// content, and anything else a file named by the request's Content-Disposition,
+// or after its type.
+func rawBody(r *http.Request, content string) api.CreateRequest {
+	contentType := DetectContentType(content)
+
+	if IsText(contentType) {
+		return api.CreateRequest{Content: content}
+	}
+
+	name := "file"
+
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Disposition")); err == nil && params["filename"] != "" {
+		name = params["filename"]
+	} else if extensions, _ := mime.ExtensionsByType(contentType); len(extensions) > 0 {
+		name += extensions[0]
+	}
+
+	return api.CreateRequest{Files: []api.CreateFile{{Name: name, Content: content, ContentType: contentType}}}
+}
+
+// rawSampleSize is how much of a streamed raw body is read up front, to detect
+// its type and language from.
+const rawSampleSize = 64 * 1024
+
+// IsRawBody reports whether a request's body is the content of a document as it
+// is, rather than JSON or a form.
+func IsRawBody(r *http.Request) bool {
+	switch strings.Split(r.Header.Get("Content-Type"), ";")[0] {
+	case "text/plain", "application/octet-stream":
+		return true
+	}
+
+	return false
+}
+
+// HandleRawBody prepares a raw body to be streamed into storage, rather than read
+// into memory. Only its start is read here, and is the content of the returned
+// request, for it to be validated and have its type and language detected. The
+// returned reader reads the whole body, failing with an *http.MaxBytesError if
+// it's more than maxSize bytes.
+func HandleRawBody(maxSize int, w http.ResponseWriter, r *http.Request) (api.CreateRequest, io.Reader, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxSize))
+	sample := make([]byte, rawSampleSize)
+	n, err := io.ReadFull(r.Body, sample)
+
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return api.CreateRequest{}, nil, err
+	}
+
+	sample = sample[:n]
+	body, err := applyQuery(rawBody(r, string(sample)), r.URL.Query())
+
+	if err != nil {
+		return api.CreateRequest{}, nil, err
 	}
 
-	return CreateRequest{}, nil
+	return body, io.MultiReader(bytes.NewReader(sample), r.Body), nil
 }
 
 // WriteJSON writes a Request payload (p) to an HTTP response writer (w)
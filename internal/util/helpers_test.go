@@ -28,21 +28,60 @@ import (
 	"testing"
 
 	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/lukewhrit/spacebin/pkg/api"
 	"github.com/stretchr/testify/require"
 )
 
 func TestValidateBody(t *testing.T) {
-	require.NoError(t, util.ValidateBody(100, util.CreateRequest{
+	require.NoError(t, util.ValidateBody(100, api.CreateRequest{
 		Content: "Test",
 	}))
 
-	require.Error(t, util.ValidateBody(2, util.CreateRequest{
+	require.Error(t, util.ValidateBody(2, api.CreateRequest{
 		Content: "Test",
 	}))
 
-	require.Error(t, util.ValidateBody(2, util.CreateRequest{
+	require.Error(t, util.ValidateBody(2, api.CreateRequest{
 		Content: "",
 	}))
+
+	require.Error(t, util.ValidateBody(100, api.CreateRequest{
+		Content:   "Test",
+		ExpiresIn: -1,
+	}))
+
+	require.NoError(t, util.ValidateBody(100, api.CreateRequest{
+		Content:  "Test",
+		Language: "go",
+	}))
+
+	require.Error(t, util.ValidateBody(100, api.CreateRequest{
+		Content:  "Test",
+		Language: "not-a-language",
+	}))
+}
+
+func TestValidateBodyFiles(t *testing.T) {
+	files := []api.CreateFile{{Name: "config.yml", Content: "debug: true"}, {Name: "app.log", Content: "started"}}
+
+	require.NoError(t, util.ValidateBody(100, api.CreateRequest{Files: files}))
+
+	// Files replace content, and have to fit within the maximum size together
+	require.Error(t, util.ValidateBody(100, api.CreateRequest{Content: "Test", Files: files}))
+	require.Error(t, util.ValidateBody(15, api.CreateRequest{Files: files}))
+
+	tests := map[string]api.CreateFile{
+		"Duplicate Name": {Name: "app.log", Content: "again"},
+		"No Name":        {Content: "test"},
+		"Slash":          {Name: "../app.log", Content: "test"},
+		"Dot":            {Name: "..", Content: "test"},
+		"No Content":     {Name: "empty.txt"},
+		"Language":       {Name: "test.txt", Content: "test", Language: "not-a-language"},
+	}
+
+	for name, file := range tests {
+		require.Error(t, util.ValidateBody(100, api.CreateRequest{Files: append(files, file)}), name)
+	}
 }
 
 func TestHandleBodyJSON(t *testing.T) {
@@ -53,7 +92,7 @@ func TestHandleBodyJSON(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPost, "/", &buf)
 	req.Header.Set("Content-Type", "application/json")
-	body, err := util.HandleBody(400000, req)
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
 
 	require.NoError(t, err)
 	require.Equal(t, "Hello, world!", body.Content)
@@ -68,15 +107,244 @@ func TestHandleBodyMultipart(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodPost, "/", &buf)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
-	body, err := util.HandleBody(400000, req)
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, "Hello, world!", body.Content)
+}
+
+func TestHandleBodyFiles(t *testing.T) {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"files": []map[string]string{
+			{"name": "config.yml", "content": "debug: true"},
+			{"name": "app.log", "content": "started", "language": "plaintext"},
+		},
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", "application/json")
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, []api.CreateFile{
+		{Name: "config.yml", Content: "debug: true"},
+		{Name: "app.log", Content: "started", Language: "plaintext"},
+	}, body.Files)
+
+	// Every file part named "files" is a file
+	buf.Reset()
+	writer := multipart.NewWriter(&buf)
+
+	for _, file := range body.Files {
+		fw, _ := writer.CreateFormFile("files", file.Name)
+		io.WriteString(fw, file.Content)
+	}
+
+	writer.Close()
+
+	req = httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	body, err = util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, []api.CreateFile{
+		{Name: "config.yml", Content: "debug: true", ContentType: util.TextContentType},
+		{Name: "app.log", Content: "started", ContentType: util.TextContentType},
+	}, body.Files)
+}
+
+func TestHandleBodyFile(t *testing.T) {
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fw, _ := writer.CreateFormFile("file", "logo.png")
+	io.WriteString(fw, image)
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, []api.CreateFile{
+		{Name: "logo.png", Content: image, ContentType: "image/png"},
+	}, body.Files)
+}
+
+func TestHandleBodyRaw(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader("Hello, world!"))
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
 
 	require.NoError(t, err)
 	require.Equal(t, "Hello, world!", body.Content)
+
+	// Raw bodies that aren't text are uploaded as a file
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(image))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Content-Disposition", `attachment; filename="logo.png"`)
+	body, err = util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, "", body.Content)
+	require.Equal(t, []api.CreateFile{{Name: "logo.png", Content: image, ContentType: "image/png"}}, body.Files)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(image))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	body, err = util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, "file.png", body.Files[0].Name)
+}
+
+func TestHandleRawBody(t *testing.T) {
+	// Only the start of the body is read up front, but all of it can be read after
+	content := strings.Repeat("a", 100_000)
+
+	req := httptest.NewRequest(http.MethodPost, "/?language=go", strings.NewReader(content))
+	req.Header.Set("Content-Type", "text/plain")
+	require.True(t, util.IsRawBody(req))
+
+	body, r, err := util.HandleRawBody(400000, httptest.NewRecorder(), req)
+	require.NoError(t, err)
+	require.Less(t, len(body.Content), len(content))
+	require.Equal(t, "go", body.Language)
+
+	read, err := io.ReadAll(r)
+	require.NoError(t, err)
+	require.Equal(t, content, string(read))
+
+	// Bodies that aren't text are described as a file
+	image := "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(image))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	body, _, err = util.HandleRawBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, "file.png", body.Files[0].Name)
+	require.Equal(t, "image/png", body.Files[0].ContentType)
+
+	// Bodies that are too large fail once they're read past the limit
+	var tooLarge *http.MaxBytesError
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(content))
+	req.Header.Set("Content-Type", "text/plain")
+	_, r, err = util.HandleRawBody(99_999, httptest.NewRecorder(), req)
+	require.NoError(t, err)
+
+	_, err = io.ReadAll(r)
+	require.ErrorAs(t, err, &tooLarge)
+
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("{}"))
+	req.Header.Set("Content-Type", "application/json")
+	require.False(t, util.IsRawBody(req))
+}
+
+func TestHandleBodyQuery(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?language=go&expires_in=60&burn_after_read=true",
+		strings.NewReader("package main"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, api.CreateRequest{
+		Content:       "package main",
+		ExpiresIn:     60,
+		BurnAfterRead: true,
+		Language:      "go",
+	}, body)
+
+	req = httptest.NewRequest(http.MethodPost, "/?expires_in=soon", strings.NewReader("package main"))
+	req.Header.Set("Content-Type", "text/plain")
+	_, err = util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.Error(t, err)
+}
+
+func TestHandleBodyForm(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/?language=go",
+		strings.NewReader("content=a+%26+b&expires_in=60&burn_after_read=true&language=python"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, api.CreateRequest{
+		Content:       "a & b",
+		ExpiresIn:     60,
+		BurnAfterRead: true,
+		Language:      "go",
+	}, body)
+
+	// Forms without content are just text sent by curl
+	req = httptest.NewRequest(http.MethodPost, "/", strings.NewReader("x = 1 + 2"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	body, err = util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, "x = 1 + 2", body.Content)
+}
+
+func TestHandleBodyTooLarge(t *testing.T) {
+	var tooLarge *http.MaxBytesError
+
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat("a", 101)))
+	req.Header.Set("Content-Type", "text/plain")
+	_, err := util.HandleBody(100, httptest.NewRecorder(), req)
+
+	require.ErrorAs(t, err, &tooLarge)
+
+	// Multipart bodies are cut off at the same size, however they're split up
+	var buf bytes.Buffer
+	writer := multipart.NewWriter(&buf)
+	fw, _ := writer.CreateFormFile("file", "big.bin")
+	io.WriteString(fw, strings.Repeat("\x00", 200))
+	writer.Close()
+
+	req = httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	_, err = util.HandleBody(100, httptest.NewRecorder(), req)
+
+	require.ErrorAs(t, err, &tooLarge)
+}
+
+func TestHandleBodyExpiresIn(t *testing.T) {
+	var buf bytes.Buffer
+	json.NewEncoder(&buf).Encode(map[string]interface{}{
+		"content":    "Hello, world!",
+		"expires_in": 3600,
+	})
+
+	req := httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", "application/json")
+
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(3600), body.ExpiresIn)
+
+	buf.Reset()
+	writer := multipart.NewWriter(&buf)
+	writer.WriteField("content", "Hello, world!")
+	writer.WriteField("expires_in", "86400")
+	writer.Close()
+
+	req = httptest.NewRequest(http.MethodPost, "/", &buf)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	body, err = util.HandleBody(400000, httptest.NewRecorder(), req)
+
+	require.NoError(t, err)
+	require.Equal(t, int64(86400), body.ExpiresIn)
 }
 
 func TestHandleBodyNoContent(t *testing.T) {
 	req := httptest.NewRequest(http.MethodPost, "/", &bytes.Buffer{})
-	body, err := util.HandleBody(400000, req)
+	body, err := util.HandleBody(400000, httptest.NewRecorder(), req)
 
 	require.NoError(t, err)
 	require.Equal(t, "", body.Content)
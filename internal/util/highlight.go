@@ -2,6 +2,7 @@ package util
 
 import (
 	"strings"
+	"sync"
 
 	"github.com/alecthomas/chroma/v2"
 	"github.com/alecthomas/chroma/v2/formatters/html"
@@ -9,18 +10,120 @@ import (
 	"github.com/alecthomas/chroma/v2/styles"
 )
 
+const (
+	// AutoTheme highlights documents with LightStyle or DarkStyle, whichever
+	// matches the colour scheme the reader prefers. Every other theme is the
+	// name of a Chroma style.
+	AutoTheme = "auto"
+
+	LightStyle = "github"
+	DarkStyle  = "monokai"
+)
+
+// formatterOptions render highlighted code as HTML with line numbers, using
+// classes so the colours can come from a shared stylesheet.
+var formatterOptions = []html.Option{html.WithLineNumbers(true), html.WithClasses(true)}
+
+// formatter is shared by everything highlighted with the default options.
+var formatter = html.New(append(formatterOptions, html.WithLinkableLineNumbers(true, "L"))...)
+
+// HighlightOptions change how Highlight renders code.
+type HighlightOptions struct {
+	Lines      [][2]int // Inclusive ranges of lines to mark, so they stand out
+	LinePrefix string   // Prefix of the IDs line numbers link to, "L" if blank
+}
+
+// stylesheets holds the CSS for each theme that's been used, by name.
+var stylesheets sync.Map
+
+// getStyle returns the Chroma style with the given name, or the fallback style
+// if there isn't one.
+func getStyle(name string) *chroma.Style {
+	style := styles.Get(name)
+
+	if style == nil {
+		style = styles.Fallback
+	}
+
+	return style
+}
+
+// Themes lists every theme documents can be highlighted with.
+func Themes() []string {
+	return append([]string{AutoTheme}, styles.Names()...)
+}
+
+// IsTheme reports whether name is one of the themes listed by Themes.
+func IsTheme(name string) bool {
+	_, ok := styles.Registry[name]
+	return ok || name == AutoTheme
+}
+
+// ThemeScheme returns whether a theme is "light" or "dark", going by its
+// background colour. For AutoTheme, it's "auto".
+func ThemeScheme(theme string) string {
+	if theme == AutoTheme {
+		return "auto"
+	}
+
+	background := getStyle(theme).Get(chroma.Background).Background
+
+	if background.IsSet() && background.Brightness() < 0.5 {
+		return "dark"
+	}
+
+	return "light"
+}
+
+// Language returns the name of the Chroma lexer for a language, which can be
+// given by its name, one of its aliases or a file extension. It returns false
+// if Chroma doesn't know the language.
+func Language(name string) (string, bool) {
+	lexer := lexers.Get(name)
+
+	if lexer == nil {
+		return "", false
+	}
+
+	return lexer.Config().Name, true
+}
+
+// DetectLanguage guesses the language of code, returning the name of the
+// plaintext lexer if it can't tell.
+func DetectLanguage(code string) string {
+	lexer := lexers.Analyse(code)
+
+	if lexer == nil {
+		return "plaintext"
+	}
+
+	return lexer.Config().Name
+}
+
+// DetectFileLanguage guesses the language of a file from its name, or from its
+// content if the name doesn't give it away.
+func DetectFileLanguage(name, code string) string {
+	if lexer := lexers.Match(name); lexer != nil {
+		return lexer.Config().Name
+	}
+
+	return DetectLanguage(code)
+}
+
 // Highlight uses Chroma to highlight code in documents.
-func Highlight(code string, extension string) (string, string, error) {
+func Highlight(code string, language string, theme string, options HighlightOptions) (string, error) {
 	// This function is used to highlight code in documents.
 	// It uses the Chroma library to parse and highlight code.
-	// The Chroma lexer is determined by the document's language.
-	// If the document does not have a language, the lexer is set to plaintext.
-	// The highlighted code is then returned as a string containing HTML.
+	// The Chroma lexer is determined by the document's language, which may be
+	// a lexer name, alias or file extension. If it isn't given, it's guessed,
+	// and if it can't be found, the lexer is set to plaintext.
+	// The highlighted code is then returned as a string containing HTML, to be
+	// used along with the stylesheet from HighlightCSS.
 
 	var lexer chroma.Lexer
 
-	if extension != "" {
-		lexer = lexers.Get(extension)
+	if language != "" {
+		lexer = lexers.Get(language)
 	} else {
 		lexer = lexers.Analyse(code)
 	}
@@ -29,31 +132,101 @@ func Highlight(code string, extension string) (string, string, error) {
 		lexer = lexers.Fallback
 	}
 
-	style := styles.Get("monokai")
-	if style == nil {
-		style = styles.Fallback
-	}
-
-	formatter := html.New(html.WithLineNumbers(true), html.WithLinkableLineNumbers(true, "L"), html.WithClasses(true))
 	iterator, err := lexer.Tokenise(nil, code)
 
 	if err != nil {
-		return "", "", err
+		return "", err
+	}
+
+	f := formatter
+
+	if options.LinePrefix != "" {
+		f = html.New(append(formatterOptions, html.WithLinkableLineNumbers(true, options.LinePrefix))...)
 	}
 
 	w := new(strings.Builder)
-	err = formatter.Format(w, style, iterator)
+	err = f.Format(w, getStyle(theme), iterator)
 
 	if err != nil {
-		return "", "", err
+		return "", err
+	}
+
+	return MarkLines(w.String(), options.Lines), nil
+}
+
+// lineStart and markedLineStart open each line of highlighted code, depending
+// on whether it's marked. Content is escaped, so they can't appear inside it.
+const (
+	lineStart       = `<span class="line">`
+	markedLineStart = `<span class="line hl">`
+)
+
+// MarkLines marks inclusive ranges of lines in code highlighted by Highlight,
+// so highlighted code can be kept unmarked and marked as it's shown.
+func MarkLines(highlighted string, lines [][2]int) string {
+	if len(lines) == 0 {
+		return highlighted
+	}
+
+	var b strings.Builder
+	b.Grow(len(highlighted) + len(lines)*len(" hl"))
+
+	for n := 1; ; n++ {
+		i := strings.Index(highlighted, lineStart)
+
+		if i < 0 {
+			break
+		}
+
+		b.WriteString(highlighted[:i])
+		highlighted = highlighted[i+len(lineStart):]
+
+		if isMarked(lines, n) {
+			b.WriteString(markedLineStart)
+		} else {
+			b.WriteString(lineStart)
+		}
+	}
+
+	b.WriteString(highlighted)
+	return b.String()
+}
+
+// isMarked reports whether line n is in one of the ranges.
+func isMarked(lines [][2]int, n int) bool {
+	for _, r := range lines {
+		if n >= r[0] && n <= r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// HighlightCSS returns the stylesheet for code highlighted with a theme. It's
+// only generated the first time each theme is asked for.
+func HighlightCSS(theme string) (string, error) {
+	if css, ok := stylesheets.Load(theme); ok {
+		return css.(string), nil
 	}
 
 	css := new(strings.Builder)
-	err = formatter.WriteCSS(css, style)
 
-	if err != nil {
-		return "", "", err
+	if theme == AutoTheme {
+		// Both styles' rules, each only applying to its colour scheme
+		for _, scheme := range []struct{ name, style string }{{"light", LightStyle}, {"dark", DarkStyle}} {
+			css.WriteString("@media (prefers-color-scheme: " + scheme.name + ") {\n")
+
+			if err := formatter.WriteCSS(css, getStyle(scheme.style)); err != nil {
+				return "", err
+			}
+
+			css.WriteString("}\n")
+		}
+	} else if err := formatter.WriteCSS(css, getStyle(theme)); err != nil {
+		return "", err
 	}
 
-	return w.String(), css.String(), nil
+	stylesheets.Store(theme, css.String())
+	return css.String(), nil
 }
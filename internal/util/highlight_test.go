@@ -1,7 +1,11 @@
 package util
 
 import (
+	"strings"
 	"testing"
+
+	chromaHTML "github.com/alecthomas/chroma/v2/formatters/html"
+	"github.com/alecthomas/chroma/v2/lexers"
 )
 
 func TestHighlight(t *testing.T) {
@@ -47,7 +51,7 @@ func TestHighlight(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			gotHTML, gotCSS, err := Highlight(tt.code, tt.extension)
+			gotHTML, err := Highlight(tt.code, tt.extension, AutoTheme, HighlightOptions{})
 			if (err != nil) != tt.expectError {
 				t.Errorf("Highlight() error = %v, wantErr %v", err, tt.expectError)
 				return
@@ -55,9 +59,178 @@ func TestHighlight(t *testing.T) {
 
 			// Since the output is dependent on the Chroma library, it's tricky to hardcode expected values.
 			// We can, however, validate that the result is not empty when it should be valid.
-			if !tt.expectError && (gotHTML == "" || gotCSS == "") {
-				t.Errorf("Expected non-empty HTML and CSS, gotHTML = %v, gotCSS = %v", gotHTML, gotCSS)
+			if !tt.expectError && gotHTML == "" {
+				t.Errorf("Expected non-empty HTML, gotHTML = %v", gotHTML)
+			}
+		})
+	}
+}
+
+func TestHighlightOptions(t *testing.T) {
+	html, err := Highlight("one\ntwo\nthree\nfour\n", "", AutoTheme, HighlightOptions{Lines: [][2]int{{2, 3}}})
+	if err != nil {
+		t.Fatalf("Highlight() error = %v", err)
+	}
+
+	if n := strings.Count(html, `class="line hl"`); n != 2 {
+		t.Errorf("Expected 2 marked lines, got %d", n)
+	}
+
+	// The shared formatter isn't changed
+	html, _ = Highlight("one\ntwo\n", "", AutoTheme, HighlightOptions{})
+
+	if strings.Contains(html, `class="line hl"`) || !strings.Contains(html, `id="L2"`) {
+		t.Error("Expected no marked lines, and the default line IDs")
+	}
+
+	html, _ = Highlight("one\ntwo\n", "", AutoTheme, HighlightOptions{LinePrefix: "f2-L"})
+
+	if !strings.Contains(html, `id="f2-L2"`) || !strings.Contains(html, `href="#f2-L2"`) {
+		t.Error("Expected line IDs with the given prefix")
+	}
+}
+
+func TestMarkLines(t *testing.T) {
+	code := "one\ntwo\nthree\nfour\n"
+	lines := [][2]int{{1, 1}, {3, 4}}
+
+	html, err := Highlight(code, "plaintext", AutoTheme, HighlightOptions{})
+	if err != nil {
+		t.Fatalf("Highlight() error = %v", err)
+	}
+
+	// Lines are marked just as Chroma marks them
+	iterator, _ := lexers.Get("plaintext").Tokenise(nil, code)
+	want := new(strings.Builder)
+	chromaHTML.New(append(formatterOptions, chromaHTML.WithLinkableLineNumbers(true, "L"),
+		chromaHTML.HighlightLines(lines))...).Format(want, getStyle(AutoTheme), iterator)
+
+	if got := MarkLines(html, lines); got != want.String() {
+		t.Errorf("MarkLines() = %v, want %v", got, want.String())
+	}
+
+	if got := MarkLines(html, nil); got != html {
+		t.Errorf("MarkLines() without lines = %v, want %v", got, html)
+	}
+}
+
+func TestLanguage(t *testing.T) {
+	tests := map[string]string{"go": "Go", "Go": "Go", "py": "Python", "golang": "Go", "plaintext": "plaintext"}
+
+	for name, want := range tests {
+		if got, ok := Language(name); !ok || got != want {
+			t.Errorf("Language(%q) = %q, %v, want %q", name, got, ok, want)
+		}
+	}
+
+	for _, name := range []string{"", "not-a-language"} {
+		if _, ok := Language(name); ok {
+			t.Errorf("Language(%q) found a lexer", name)
+		}
+	}
+}
+
+func TestDetectLanguage(t *testing.T) {
+	if got := DetectLanguage("#!/bin/bash\necho hi"); got != "Bash" {
+		t.Errorf("DetectLanguage() = %q, want Bash", got)
+	}
+
+	if got := DetectLanguage("Just some text."); got != "plaintext" {
+		t.Errorf("DetectLanguage() = %q, want plaintext", got)
+	}
+}
+
+func TestDetectFileLanguage(t *testing.T) {
+	tests := map[string]string{"main.go": "Go", "Makefile": "Makefile", "notes": "plaintext"}
+
+	for name, want := range tests {
+		if got := DetectFileLanguage(name, "Just some text."); got != want {
+			t.Errorf("DetectFileLanguage(%q) = %q, want %q", name, got, want)
+		}
+	}
+
+	// Names without a known extension fall back to the content
+	if got := DetectFileLanguage("run", "#!/bin/bash\necho hi"); got != "Bash" {
+		t.Errorf("DetectFileLanguage() = %q, want Bash", got)
+	}
+}
+
+func TestHighlightCSS(t *testing.T) {
+	css, err := HighlightCSS(DarkStyle)
+	if err != nil {
+		t.Fatalf("HighlightCSS() error = %v", err)
+	}
+
+	if css == "" {
+		t.Error("Expected non-empty CSS")
+	}
+
+	// The automatic theme has rules for both colour schemes
+	css, err = HighlightCSS(AutoTheme)
+	if err != nil {
+		t.Fatalf("HighlightCSS() error = %v", err)
+	}
+
+	for _, scheme := range []string{"light", "dark"} {
+		if !strings.Contains(css, "@media (prefers-color-scheme: "+scheme+")") {
+			t.Errorf("Expected rules for the %s colour scheme", scheme)
+		}
+	}
+
+	// Unknown styles fall back to Chroma's default
+	fallback, err := HighlightCSS("not-a-style")
+	if err != nil || fallback == "" {
+		t.Errorf("HighlightCSS() fallback = %q, error = %v", fallback, err)
+	}
+}
+
+func TestThemes(t *testing.T) {
+	themes := Themes()
+
+	if themes[0] != AutoTheme {
+		t.Errorf("Expected %q to be listed first, got %q", AutoTheme, themes[0])
+	}
+
+	for _, theme := range themes {
+		if !IsTheme(theme) {
+			t.Errorf("IsTheme(%q) = false for a listed theme", theme)
+		}
+	}
+
+	if IsTheme("not-a-style") {
+		t.Error("IsTheme() = true for an unknown theme")
+	}
+
+	tests := map[string]string{AutoTheme: "auto", DarkStyle: "dark", LightStyle: "light"}
+
+	for theme, want := range tests {
+		if got := ThemeScheme(theme); got != want {
+			t.Errorf("ThemeScheme(%q) = %q, want %q", theme, got, want)
+		}
+	}
+}
+
+// benchmarkLog is a document the size of the default SPIRIT_MAX_SIZE.
+var benchmarkLog = strings.Repeat("2024-01-01T00:00:00Z INFO server: handled request method=GET status=200\n", 400_000/73)
+
+func BenchmarkHighlight(b *testing.B) {
+	for _, extension := range []string{"", "txt"} {
+		b.Run("extension="+extension, func(b *testing.B) {
+			b.SetBytes(int64(len(benchmarkLog)))
+
+			for i := 0; i < b.N; i++ {
+				if _, err := Highlight(benchmarkLog, extension, AutoTheme, HighlightOptions{}); err != nil {
+					b.Fatal(err)
+				}
 			}
 		})
 	}
 }
+
+func BenchmarkHighlightCSS(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if _, err := HighlightCSS(AutoTheme); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseLineRanges parses a comma-separated list of line numbers and ranges,
+// like "10-25,40", into inclusive [start, end] pairs. Lines are numbered from 1.
+func ParseLineRanges(s string) ([][2]int, error) {
+	var ranges [][2]int
+
+	for _, part := range strings.Split(s, ",") {
+		start, end, isRange := strings.Cut(strings.TrimSpace(part), "-")
+
+		first, err := strconv.Atoi(start)
+
+		if err != nil || first < 1 {
+			return nil, fmt.Errorf("invalid line number %q", start)
+		}
+
+		last := first
+
+		if isRange {
+			last, err = strconv.Atoi(end)
+
+			if err != nil || last < first {
+				return nil, fmt.Errorf("invalid line range %q", part)
+			}
+		}
+
+		ranges = append(ranges, [2]int{first, last})
+	}
+
+	return ranges, nil
+}
+
+// SliceLines returns the lines of content that fall within any of ranges, in
+// the order they appear. Ranges past the end of content are ignored.
+func SliceLines(content string, ranges [][2]int) string {
+	lines := strings.SplitAfter(content, "\n")
+	slice := new(strings.Builder)
+
+	for i, line := range lines {
+		for _, r := range ranges {
+			if i+1 >= r[0] && i+1 <= r[1] {
+				slice.WriteString(line)
+				break
+			}
+		}
+	}
+
+	return slice.String()
+}
@@ -0,0 +1,43 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLineRanges(t *testing.T) {
+	ranges, err := util.ParseLineRanges("10-25,40")
+	require.NoError(t, err)
+	require.Equal(t, [][2]int{{10, 25}, {40, 40}}, ranges)
+
+	for _, s := range []string{"", "0", "a", "25-10", "10-", "-5", "1,,2"} {
+		_, err := util.ParseLineRanges(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestSliceLines(t *testing.T) {
+	content := "one\ntwo\nthree\nfour\nfive"
+
+	require.Equal(t, "two\nthree\n", util.SliceLines(content, [][2]int{{2, 3}}))
+	require.Equal(t, "one\nfour\nfive", util.SliceLines(content, [][2]int{{4, 10}, {1, 1}}))
+	require.Equal(t, "", util.SliceLines(content, [][2]int{{6, 8}}))
+}
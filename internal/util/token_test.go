@@ -0,0 +1,45 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateToken(t *testing.T) {
+	a, err := util.GenerateToken()
+	require.NoError(t, err)
+	require.Len(t, a, 43)
+
+	b, err := util.GenerateToken()
+	require.NoError(t, err)
+	require.NotEqual(t, a, b)
+}
+
+func TestCheckToken(t *testing.T) {
+	token, _ := util.GenerateToken()
+	hash := util.HashToken(token)
+
+	require.Len(t, hash, 64)
+	require.True(t, util.CheckToken(token, hash))
+	require.False(t, util.CheckToken("wrong", hash))
+	require.False(t, util.CheckToken("", hash))
+	require.False(t, util.CheckToken("", ""))
+}
@@ -0,0 +1,283 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Permission is what a user let in with Basic Auth is allowed to do.
+type Permission int
+
+const (
+	// PermissionRead lets users view documents, but not create them.
+	PermissionRead Permission = iota
+	// PermissionCreate lets users create, edit and delete documents too.
+	PermissionCreate
+)
+
+// ParsePermission parses a permission as it's written in the users file.
+func ParsePermission(s string) (Permission, error) {
+	switch s {
+	case "read":
+		return PermissionRead, nil
+	case "create":
+		return PermissionCreate, nil
+	default:
+		return 0, fmt.Errorf("unknown permission %q", s)
+	}
+}
+
+func (p Permission) String() string {
+	if p == PermissionCreate {
+		return "create"
+	}
+
+	return "read"
+}
+
+// User is someone allowed in with Basic Auth. Only a bcrypt or argon2id hash
+// of their password is kept.
+type User struct {
+	Name       string
+	Hash       string
+	Permission Permission
+}
+
+// Users are the users allowed in with Basic Auth.
+type Users struct {
+	users map[string]User
+
+	// Password hashes are slow to check on purpose, and browsers send
+	// credentials with every request, so those that have been checked are
+	// remembered. They're kept hashed, mapped to the user's name.
+	mu       sync.Mutex
+	verified map[[sha256.Size]byte]string
+}
+
+// NewUsers returns an empty set of users.
+func NewUsers() *Users {
+	return &Users{users: map[string]User{}, verified: map[[sha256.Size]byte]string{}}
+}
+
+// Add adds a user, checking that their hash is one that can be verified.
+func (u *Users) Add(user User) error {
+	if user.Name == "" || strings.Contains(user.Name, ":") {
+		return fmt.Errorf("invalid user name %q", user.Name)
+	}
+
+	if _, ok := u.users[user.Name]; ok {
+		return fmt.Errorf("user %q is listed more than once", user.Name)
+	}
+
+	if err := checkHash(user.Hash); err != nil {
+		return fmt.Errorf("user %q: %w", user.Name, err)
+	}
+
+	u.users[user.Name] = user
+	return nil
+}
+
+// Len returns the number of users.
+func (u *Users) Len() int {
+	return len(u.users)
+}
+
+// ParseUsers reads users, one on each line, in the form name:hash:permission,
+// where permission is read or create. Users without a permission can only
+// read. Blank lines and lines starting with # are ignored.
+func ParseUsers(r io.Reader) (*Users, error) {
+	users := NewUsers()
+	scanner := bufio.NewScanner(r)
+
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.Split(line, ":")
+
+		if len(parts) < 2 || len(parts) > 3 {
+			return nil, fmt.Errorf("users file invalid: line %d isn't name:hash:permission", n)
+		}
+
+		user := User{Name: parts[0], Hash: parts[1]}
+
+		if len(parts) == 3 {
+			var err error
+
+			if user.Permission, err = ParsePermission(parts[2]); err != nil {
+				return nil, fmt.Errorf("users file invalid: line %d: %w", n, err)
+			}
+		}
+
+		if err := users.Add(user); err != nil {
+			return nil, fmt.Errorf("users file invalid: line %d: %w", n, err)
+		}
+	}
+
+	return users, scanner.Err()
+}
+
+// LoadUsers reads the users in a file written for ParseUsers. If path is
+// blank, there are none.
+func LoadUsers(path string) (*Users, error) {
+	if path == "" {
+		return NewUsers(), nil
+	}
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	return ParseUsers(f)
+}
+
+// dummyHash is checked against when no user has the name given, so it takes
+// as long to turn away an unknown user as it does a wrong password.
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("spacebin"), bcrypt.DefaultCost)
+	return hash
+})
+
+// Authenticate returns the user with a name and password, and reports whether
+// there is one.
+func (u *Users) Authenticate(name, password string) (User, bool) {
+	key := sha256.Sum256([]byte(name + ":" + password))
+
+	u.mu.Lock()
+	verified, ok := u.verified[key]
+	u.mu.Unlock()
+
+	user, exists := u.users[name]
+
+	if ok && verified == name && exists {
+		return user, true
+	}
+
+	if !exists {
+		bcrypt.CompareHashAndPassword(dummyHash(), []byte(password))
+		return User{}, false
+	}
+
+	if !CheckPassword(user.Hash, password) {
+		return User{}, false
+	}
+
+	u.mu.Lock()
+	u.verified[key] = name
+	u.mu.Unlock()
+
+	return user, true
+}
+
+// HashPassword hashes a password with bcrypt, for the users file.
+func HashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(hash), err
+}
+
+// CheckPassword reports whether a password matches a bcrypt or argon2id hash.
+func CheckPassword(hash, password string) bool {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		params, salt, key, err := parseArgon2Hash(hash)
+
+		if err != nil {
+			return false
+		}
+
+		derived := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(key)))
+		return subtle.ConstantTimeCompare(derived, key) == 1
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// checkHash returns an error if hash isn't a bcrypt or argon2id hash.
+func checkHash(hash string) error {
+	if strings.HasPrefix(hash, "$argon2id$") {
+		_, _, _, err := parseArgon2Hash(hash)
+		return err
+	}
+
+	if _, err := bcrypt.Cost([]byte(hash)); err != nil {
+		return errors.New("password hash isn't bcrypt or argon2id")
+	}
+
+	return nil
+}
+
+type argon2Params struct {
+	memory  uint32 // in KiB
+	time    uint32
+	threads uint8
+}
+
+// parseArgon2Hash parses an argon2id hash in the usual encoding, like
+// $argon2id$v=19$m=65536,t=3,p=4$salt$key, with the salt and key in base64.
+func parseArgon2Hash(hash string) (argon2Params, []byte, []byte, error) {
+	var params argon2Params
+	var version int
+	invalid := errors.New("invalid argon2id hash")
+
+	parts := strings.Split(hash, "$")
+
+	if len(parts) != 6 {
+		return params, nil, nil, invalid
+	}
+
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return params, nil, nil, invalid
+	}
+
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time,
+		&params.threads); err != nil || params.time == 0 || params.threads == 0 {
+		return params, nil, nil, invalid
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+
+	if err != nil {
+		return params, nil, nil, invalid
+	}
+
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+
+	if err != nil || len(key) == 0 {
+		return params, nil, nil, invalid
+	}
+
+	return params, salt, key, nil
+}
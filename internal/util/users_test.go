@@ -0,0 +1,111 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package util_test
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/util"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func bcryptHash(t *testing.T, password string) string {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	require.NoError(t, err)
+
+	return string(hash)
+}
+
+func argon2Hash(password string) string {
+	salt := []byte("0123456789abcdef")
+	key := argon2.IDKey([]byte(password), salt, 1, 1024, 1, 32)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=1024,t=1,p=1$%s$%s", argon2.Version,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+func TestParseUsers(t *testing.T) {
+	users, err := util.ParseUsers(strings.NewReader(fmt.Sprintf(`# Viewers
+viewer:%s:read
+legacy:%s
+
+writer:%s:create
+`, bcryptHash(t, "hunter2"), bcryptHash(t, "legacy"), argon2Hash("correct horse"))))
+	require.NoError(t, err)
+	require.Equal(t, 3, users.Len())
+
+	user, ok := users.Authenticate("viewer", "hunter2")
+	require.True(t, ok)
+	require.Equal(t, util.PermissionRead, user.Permission)
+
+	// Users without a permission can only read
+	user, ok = users.Authenticate("legacy", "legacy")
+	require.True(t, ok)
+	require.Equal(t, util.PermissionRead, user.Permission)
+
+	user, ok = users.Authenticate("writer", "correct horse")
+	require.True(t, ok)
+	require.Equal(t, util.PermissionCreate, user.Permission)
+
+	// Checked credentials are remembered, but only for the right password
+	_, ok = users.Authenticate("writer", "correct horse")
+	require.True(t, ok)
+
+	_, ok = users.Authenticate("writer", "hunter2")
+	require.False(t, ok)
+
+	_, ok = users.Authenticate("viewer", "")
+	require.False(t, ok)
+
+	_, ok = users.Authenticate("nobody", "hunter2")
+	require.False(t, ok)
+}
+
+func TestParseUsersInvalid(t *testing.T) {
+	hash := bcryptHash(t, "hunter2")
+
+	for _, file := range []string{
+		"viewer",
+		"viewer:hunter2:read",                   // Not hashed
+		"viewer:" + hash + ":admin",             // Unknown permission
+		"viewer:" + hash + "\nviewer:" + hash,   // Listed twice
+		":" + hash,                              // No name
+		"viewer:$argon2id$v=19$m=1024$salt$key", // Missing parameters
+	} {
+		_, err := util.ParseUsers(strings.NewReader(file))
+		require.Error(t, err, file)
+	}
+}
+
+func TestLoadUsersBlank(t *testing.T) {
+	users, err := util.LoadUsers("")
+	require.NoError(t, err)
+	require.Zero(t, users.Len())
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := util.HashPassword("hunter2")
+	require.NoError(t, err)
+
+	require.True(t, util.CheckPassword(hash, "hunter2"))
+	require.False(t, util.CheckPassword(hash, "hunter3"))
+}
@@ -0,0 +1,140 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package api holds the types the Spacebin API sends and receives. It has no
+// dependencies, so clients can use it without pulling in the server.
+package api
+
+import "time"
+
+const (
+	// DeleteTokenHeader is the request header that carries a document's delete token.
+	DeleteTokenHeader = "X-Delete-Token"
+
+	// EditTokenHeader is the request header that carries a document's edit token.
+	EditTokenHeader = "X-Edit-Token"
+)
+
+// Document is a document as the API returns it.
+type Document struct {
+	ID            string     `json:"id"`
+	Content       string     `json:"content"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	ExpiresAt     *time.Time `json:"expires_at"`      // nil if the document never expires
+	BurnAfterRead bool       `json:"burn_after_read"` // Delete the document once it has been read
+
+	// SHA-256 hash of the content. Documents with the same content have the same
+	// hash, so it can be compared to find duplicates.
+	ContentHash string `json:"content_hash"`
+	// Name of the Chroma lexer the document is highlighted with, either chosen by
+	// the uploader or detected when it was created. Empty for custom documents.
+	Language string `json:"language"`
+	// MIME type of the content. Empty for documents from before types were
+	// recorded, which are all text.
+	ContentType string `json:"content_type"`
+	// Size of the content in bytes. For documents with several files, it's the
+	// size of all of them together.
+	Size int64 `json:"size"`
+	// Files of a document uploaded with several, in order. Nil for documents
+	// with a single, unnamed file.
+	Files []File `json:"files,omitempty"`
+}
+
+// File is one of the files of a document uploaded with several. The document's
+// own Content and Language are those of its first file.
+type File struct {
+	Name        string `json:"name"`
+	Language    string `json:"language"`
+	ContentType string `json:"content_type"` // See Document.ContentType
+	Content     string `json:"content"`
+	ContentHash string `json:"content_hash"` // See Document.ContentHash
+	Size        int64  `json:"size"`         // See Document.Size
+}
+
+// CreateRequest describes a new document.
+type CreateRequest struct {
+	Content       string       `json:"content"`
+	ExpiresIn     int64        `json:"expires_in"`      // Seconds until the document expires, 0 for the server default
+	BurnAfterRead bool         `json:"burn_after_read"` // Delete the document after it's first viewed
+	Language      string       `json:"language"`        // Chroma lexer to highlight the document with, detected if blank
+	Files         []CreateFile `json:"files"`           // Named files to upload together, instead of content
+}
+
+// CreateFile is one of several files uploaded as a single document.
+type CreateFile struct {
+	Name     string `json:"name"`
+	Content  string `json:"content"`
+	Language string `json:"language"` // Detected from the file's name or content if blank
+
+	// ContentType is sniffed from files uploaded in multipart forms. Files sent
+	// as JSON are always text.
+	ContentType string `json:"-"`
+}
+
+// CreateResponse is the payload returned by the API when a document is created.
+// The delete and edit tokens are only ever shown here, as only their hashes are stored.
+type CreateResponse struct {
+	Document
+	DeleteToken string `json:"delete_token"`
+	EditToken   string `json:"edit_token"`
+}
+
+// UpdateResponse is the payload returned by the API when a document is edited.
+type UpdateResponse struct {
+	Document
+	Revision int `json:"revision"`
+}
+
+// DiffResponse is the payload returned by the API for diffs in the JSON format.
+type DiffResponse struct {
+	From  string     `json:"from"`
+	To    string     `json:"to"`
+	Hunks []DiffHunk `json:"hunks"` // Empty if the documents are the same
+}
+
+// DiffHunk is a group of nearby changes in a unified diff. Each line is
+// prefixed with " " if it's unchanged, "-" if it was removed or "+" if it was added.
+type DiffHunk struct {
+	OldStart int      `json:"old_start"`
+	OldLines int      `json:"old_lines"`
+	NewStart int      `json:"new_start"`
+	NewLines int      `json:"new_lines"`
+	Lines    []string `json:"lines"`
+}
+
+// Config is an instance's public configuration, along with what clients can
+// choose from.
+type Config struct {
+	Host             string `json:"host"`
+	Port             int    `json:"port"`
+	CompressionLevel int    `json:"compression_level"`
+	Ratelimiter      string `json:"ratelimiter"` // Requests x Seconds
+
+	Headless              bool   `json:"headless"`
+	Analytics             string `json:"analytics"`
+	Theme                 string `json:"theme"` // Theme documents are highlighted with by default
+	ContentSecurityPolicy string `json:"csp"`
+
+	IDLength       int      `json:"id_length"`
+	IDType         string   `json:"id_type"`
+	MaxSize        int      `json:"max_size"`       // in bytes
+	ExpirationAge  int64    `json:"expiration_age"` // in hours
+	Documents      []string `json:"documents"`      // IDs of custom documents
+	WatchDocuments bool     `json:"watch_documents"`
+
+	Themes []string `json:"themes"` // Themes documents can be highlighted with
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+// Package client is a client for the Spacebin API.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/lukewhrit/spacebin/pkg/api"
+)
+
+type (
+	// Document is a document as the API returns it.
+	Document = api.Document
+
+	// File is one of the files of a document uploaded with several.
+	File = api.File
+
+	// CreateRequest describes a new document. Files given here are always text.
+	CreateRequest = api.CreateRequest
+
+	// CreateFile is one of several files uploaded as a single document.
+	CreateFile = api.CreateFile
+
+	// CreateResponse is a new document, along with the tokens needed to edit and
+	// delete it.
+	CreateResponse = api.CreateResponse
+
+	// UpdateResponse is an edited document, along with the number of the revision
+	// the edit made.
+	UpdateResponse = api.UpdateResponse
+
+	// DiffResponse is a unified diff between two documents, split into hunks.
+	DiffResponse = api.DiffResponse
+
+	// DiffHunk is a group of nearby changes in a diff.
+	DiffHunk = api.DiffHunk
+
+	// Config is an instance's public configuration.
+	Config = api.Config
+)
+
+// Errors that API errors match with errors.Is, depending on their status.
+var (
+	ErrBadRequest   = errors.New("bad request")
+	ErrUnauthorized = errors.New("unauthorized")
+	ErrForbidden    = errors.New("forbidden")
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrTooLarge     = errors.New("request too large")
+	ErrRateLimited  = errors.New("rate limited")
+)
+
+var statusErrors = map[int]error{
+	http.StatusBadRequest:            ErrBadRequest,
+	http.StatusUnauthorized:          ErrUnauthorized,
+	http.StatusForbidden:             ErrForbidden,
+	http.StatusNotFound:              ErrNotFound,
+	http.StatusConflict:              ErrConflict,
+	http.StatusRequestEntityTooLarge: ErrTooLarge,
+	http.StatusTooManyRequests:       ErrRateLimited,
+}
+
+// Error is an error returned by the API.
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("spacebin: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// Is reports whether target is the error for e's status, like ErrNotFound.
+func (e *Error) Is(target error) bool {
+	return statusErrors[e.StatusCode] == target
+}
+
+// Client makes requests to a Spacebin instance.
+type Client struct {
+	BaseURL    string       // URL of the instance, e.g. https://spaceb.in
+	Username   string       // Basic Auth username, if the instance requires it
+	Password   string       // Basic Auth password, if the instance requires it
+	HTTPClient *http.Client // Client to make requests with, http.DefaultClient if nil
+}
+
+// New returns a client for the instance at baseURL.
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// Create uploads a new document.
+func (c *Client) Create(ctx context.Context, req CreateRequest) (CreateResponse, error) {
+	var res CreateResponse
+	err := c.do(ctx, http.MethodPost, "/api/", nil, req, &res)
+	return res, err
+}
+
+// Get fetches a document. Fetching a burn after reading document deletes it.
+func (c *Client) Get(ctx context.Context, id string) (Document, error) {
+	var document Document
+	err := c.do(ctx, http.MethodGet, "/api/"+url.PathEscape(id), nil, nil, &document)
+	return document, err
+}
+
+// GetRaw fetches only a document's content.
+func (c *Client) GetRaw(ctx context.Context, id string) (string, error) {
+	res, err := c.send(ctx, http.MethodGet, "/api/"+url.PathEscape(id)+"/raw", nil, nil)
+
+	if err != nil {
+		return "", err
+	}
+
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+
+	if err != nil {
+		return "", err
+	}
+
+	// The raw route answers in plain text, even when it fails
+	if res.StatusCode != http.StatusOK {
+		return "", &Error{StatusCode: res.StatusCode, Message: strings.TrimSpace(string(content))}
+	}
+
+	return string(content), nil
+}
+
+// Update replaces a document's content, using the edit token it was created with.
+func (c *Client) Update(ctx context.Context, id, editToken, content string) (UpdateResponse, error) {
+	var res UpdateResponse
+	err := c.do(ctx, http.MethodPut, "/api/"+url.PathEscape(id), http.Header{api.EditTokenHeader: {editToken}},
+		CreateRequest{Content: content}, &res)
+	return res, err
+}
+
+// Delete deletes a document, using the delete token it was created with.
+func (c *Client) Delete(ctx context.Context, id, deleteToken string) error {
+	return c.do(ctx, http.MethodDelete, "/api/"+url.PathEscape(id),
+		http.Header{api.DeleteTokenHeader: {deleteToken}}, nil, nil)
+}
+
+// Diff compares two documents. Either can be a revision, given as the
+// document's ID followed by @ and the revision's number, like "abcdefgh@2".
+func (c *Client) Diff(ctx context.Context, from, to string) (DiffResponse, error) {
+	var diff DiffResponse
+	err := c.do(ctx, http.MethodGet, "/api/"+url.PathEscape(from)+"/diff/"+url.PathEscape(to)+"?format=json",
+		nil, nil, &diff)
+	return diff, err
+}
+
+// Config fetches the instance's public configuration.
+func (c *Client) Config(ctx context.Context) (Config, error) {
+	var config Config
+	err := c.do(ctx, http.MethodGet, "/config", nil, nil, &config)
+	return config, err
+}
+
+// do makes a request to a JSON API route, sending body as JSON if it's set, and
+// decoding the payload of the response into payload.
+func (c *Client) do(ctx context.Context, method, path string, header http.Header, body, payload any) error {
+	var r io.Reader
+
+	if body != nil {
+		b, err := json.Marshal(body)
+
+		if err != nil {
+			return err
+		}
+
+		r = bytes.NewReader(b)
+	}
+
+	res, err := c.send(ctx, method, path, header, r)
+
+	if err != nil {
+		return err
+	}
+
+	defer res.Body.Close()
+
+	// Every response from the API, successful or not, is wrapped in an envelope
+	var envelope struct {
+		Payload json.RawMessage `json:"payload"`
+		Error   string          `json:"error"`
+	}
+
+	if err := json.NewDecoder(res.Body).Decode(&envelope); err != nil {
+		// Errors from outside the API, like from Basic Auth or the ratelimiter, aren't JSON
+		if res.StatusCode != http.StatusOK {
+			return &Error{StatusCode: res.StatusCode, Message: http.StatusText(res.StatusCode)}
+		}
+
+		return fmt.Errorf("spacebin: decoding response: %w", err)
+	}
+
+	if res.StatusCode != http.StatusOK || envelope.Error != "" {
+		return &Error{StatusCode: res.StatusCode, Message: envelope.Error}
+	}
+
+	if payload == nil {
+		return nil
+	}
+
+	return json.Unmarshal(envelope.Payload, payload)
+}
+
+// send makes a request to the instance, with its credentials if it has any.
+func (c *Client) send(ctx context.Context, method, path string, header http.Header, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimSuffix(c.BaseURL, "/")+path, body)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.Username != "" || c.Password != "" {
+		req.SetBasicAuth(c.Username, c.Password)
+	}
+
+	client := c.HTTPClient
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return client.Do(req)
+}
@@ -0,0 +1,156 @@
+/*
+ * Copyright 2020-2024 Luke Whritenour
+
+ * Licensed under the Apache License, Version 2.0 (the "License");
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+
+ *     http://www.apache.org/licenses/LICENSE-2.0
+
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+
+package client_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/lukewhrit/spacebin/internal/config"
+	"github.com/lukewhrit/spacebin/internal/database"
+	"github.com/lukewhrit/spacebin/internal/database/databasefakes"
+	"github.com/lukewhrit/spacebin/internal/server"
+	"github.com/lukewhrit/spacebin/pkg/client"
+	"github.com/stretchr/testify/require"
+)
+
+var testConfig = config.Cfg{
+	Ratelimiter:   "200x5",
+	IDLength:      8,
+	IDType:        "key",
+	MaxSize:       400_000,
+	ExpirationAge: 720,
+	Theme:         "auto",
+}
+
+// newTestServer starts an instance backed by db, returning a client for it.
+func newTestServer(t *testing.T, cfg config.Cfg, db database.Database) *client.Client {
+	s := server.NewServer(&cfg, db)
+	s.MountMiddleware()
+	s.MountHandlers()
+
+	ts := httptest.NewServer(s.Router)
+	t.Cleanup(ts.Close)
+
+	return client.New(ts.URL)
+}
+
+func TestCreate(t *testing.T) {
+	db := &databasefakes.FakeDatabase{}
+	db.GetDocumentReturns(database.Document{ID: "12345678", Content: "Hello, world!", Language: "plaintext"}, nil)
+	c := newTestServer(t, testConfig, db)
+
+	res, err := c.Create(context.Background(), client.CreateRequest{Content: "Hello, world!", Language: "text"})
+
+	require.NoError(t, err)
+	require.Equal(t, "12345678", res.ID)
+	require.NotEmpty(t, res.DeleteToken)
+	require.NotEmpty(t, res.EditToken)
+
+	_, document := db.CreateDocumentArgsForCall(0)
+	require.Equal(t, "Hello, world!", document.Content)
+	require.Equal(t, "plaintext", document.Language)
+}
+
+func TestGet(t *testing.T) {
+	db := &databasefakes.FakeDatabase{}
+	db.GetDocumentReturns(database.Document{ID: "12345678", Content: "Hello, world!"}, nil)
+	c := newTestServer(t, testConfig, db)
+
+	document, err := c.Get(context.Background(), "12345678")
+
+	require.NoError(t, err)
+	require.Equal(t, "Hello, world!", document.Content)
+
+	content, err := c.GetRaw(context.Background(), "12345678")
+
+	require.NoError(t, err)
+	require.Equal(t, "Hello, world!", content)
+}
+
+func TestDiff(t *testing.T) {
+	db := &databasefakes.FakeDatabase{}
+	db.GetDocumentCalls(func(ctx context.Context, id string) (database.Document, error) {
+		return database.Document{ID: id, Content: "port=80\n"}, nil
+	})
+	db.GetRevisionReturns(database.Revision{DocumentID: "12345678", Revision: 1, Content: "port=8080\n"}, nil)
+	c := newTestServer(t, testConfig, db)
+
+	diff, err := c.Diff(context.Background(), "12345678@1", "87654321")
+
+	require.NoError(t, err)
+	require.Equal(t, "12345678@1", diff.From)
+	require.Len(t, diff.Hunks, 1)
+	require.Equal(t, []string{"-port=8080", "+port=80"}, diff.Hunks[0].Lines)
+}
+
+func TestErrors(t *testing.T) {
+	db := &databasefakes.FakeDatabase{}
+	db.GetDocumentReturns(database.Document{}, sql.ErrNoRows)
+	c := newTestServer(t, testConfig, db)
+
+	// Errors match the error for their status, and carry the API's message
+	_, err := c.Get(context.Background(), "12345678")
+
+	var apiErr *client.Error
+	require.ErrorIs(t, err, client.ErrNotFound)
+	require.ErrorAs(t, err, &apiErr)
+	require.Equal(t, 404, apiErr.StatusCode)
+	require.Equal(t, "sql: no rows in result set", apiErr.Message)
+
+	_, err = c.GetRaw(context.Background(), "12345678")
+	require.ErrorIs(t, err, client.ErrNotFound)
+
+	_, err = c.Get(context.Background(), "1234")
+	require.ErrorIs(t, err, client.ErrBadRequest)
+
+	_, err = c.Create(context.Background(), client.CreateRequest{Content: strings.Repeat("a", 400_001)})
+	require.ErrorIs(t, err, client.ErrTooLarge)
+
+	err = c.Delete(context.Background(), "12345678", "not-a-token")
+	require.ErrorIs(t, err, client.ErrNotFound)
+}
+
+func TestBasicAuth(t *testing.T) {
+	cfg := testConfig
+	cfg.Username, cfg.Password = "admin", "hunter2"
+
+	db := &databasefakes.FakeDatabase{}
+	db.GetDocumentReturns(database.Document{ID: "12345678", Content: "Hello, world!"}, nil)
+	c := newTestServer(t, cfg, db)
+
+	_, err := c.Get(context.Background(), "12345678")
+	require.ErrorIs(t, err, client.ErrUnauthorized)
+
+	c.Username, c.Password = "admin", "hunter2"
+
+	_, err = c.Get(context.Background(), "12345678")
+	require.NoError(t, err)
+}
+
+func TestConfig(t *testing.T) {
+	c := newTestServer(t, testConfig, &databasefakes.FakeDatabase{})
+
+	cfg, err := c.Config(context.Background())
+
+	require.NoError(t, err)
+	require.Equal(t, 400_000, cfg.MaxSize)
+	require.Contains(t, cfg.Themes, "monokai")
+}